@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"dux/token"
+	"math/big"
 	"strings"
 )
 
@@ -47,10 +48,35 @@ type IndexExpresssion struct {
 }
 
 type Identifier struct {
+	Token   token.Token
+	Value   string
+	Type    *TypeAnnotation // Optional annotation on let names and parameters
+	Binding *Binding        // Set by the resolver, nil until then
+}
+
+/*
+	A Binding locates the variable an identifier stands for, Depth environments
+	out from the one it's evaluated in: in its Slot there, or by name when Slot
+	is Global, which is how the bindings of the program's environment are kept.
+*/
+type Binding struct {
+	Depth int
+	Slot  int
+}
+
+const Global = -1
+
+/*
+	A TypeAnnotation names the expected type of a binding (i.e. x: int). The
+	evaluator ignores annotations, they only feed the static checker.
+*/
+type TypeAnnotation struct {
 	Token token.Token
-	Value string
+	Name  string
 }
 
+func (ta *TypeAnnotation) String() string { return ta.Name }
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -66,6 +92,7 @@ type InfixExpression struct {
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int // Set instead of Value for literals too large for 64 bits
 }
 
 type PrefixExpression struct {
@@ -103,15 +130,19 @@ type BlockStatement struct {
 }
 
 type FunctionLiteral struct {
-	Token      token.Token
-	Parameters []*Identifier
-	Body       *BlockStatement
+	Token       token.Token
+	Parameters  []*Identifier
+	ReturnType  *TypeAnnotation
+	Body        *BlockStatement
+	IsGenerator bool   // Set when Body yields; calling it returns a generator
+	Name        string // Set when the literal is bound with let, or is a method (i.e. Point.move)
 }
 
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+	IsTail    bool // Set when the call's value is what its enclosing function returns
 }
 
 type StringLiteral struct {
@@ -126,9 +157,340 @@ type ArrayLiteral struct {
 
 type HashLiteral struct {
 	Token token.Token
-	Pairs map[Expression]Expression
+	Pairs []HashPair // In source order, spreads included, so later keys win
+}
+
+// A pair of a hash literal, or a spread of another hash when Key is a *SpreadElement, which has no Value.
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+type RangeExpression struct {
+	Token     token.Token // The '..' or '..=' Token
+	Start     Expression
+	End       Expression
+	Step      Expression // nil when no explicit step is given
+	Inclusive bool
+}
+
+type ForExpression struct {
+	Token    token.Token // The 'for' Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (re *RangeExpression) expressionNode() {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("(")
+	out.WriteString(re.Start.String())
+	out.WriteString(re.Token.Literal)
+	out.WriteString(re.End.String())
+
+	if re.Step != nil {
+		out.WriteString(" step ")
+		out.WriteString(re.Step.String())
+	}
+
+	out.WriteString(")")
+
+	return out.String()
+}
+
+func (fe *ForExpression) expressionNode() {}
+func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *ForExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("for (")
+	out.WriteString(fe.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fe.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fe.Body.String())
+
+	return out.String()
 }
 
+type StructStatement struct {
+	Token   token.Token // The 'struct' Token
+	Name    *Identifier
+	Fields  []*Identifier
+	Methods []*MethodDefinition
+}
+
+type MethodDefinition struct {
+	Token    token.Token // The 'fn' Token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+type MemberExpression struct {
+	Token  token.Token // The '.' Token
+	Left   Expression
+	Member *Identifier
+}
+
+func (ss *StructStatement) statementNode() {}
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) String() string {
+	var out strings.Builder
+
+	fields := []string{}
+	for _, field := range ss.Fields {
+		fields = append(fields, field.String())
+	}
+
+	out.WriteString("struct ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(fields, ", "))
+
+	for _, method := range ss.Methods {
+		out.WriteString(" ")
+		out.WriteString(method.String())
+	}
+
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (md *MethodDefinition) statementNode() {}
+func (md *MethodDefinition) TokenLiteral() string { return md.Token.Literal }
+func (md *MethodDefinition) String() string {
+	params := []string{}
+	for _, p := range md.Function.Parameters {
+		params = append(params, p.String())
+	}
+
+	return "fn " + md.Name.String() + "(" + strings.Join(params, ", ") + ") { " + md.Function.Body.String() + "}"
+}
+
+func (me *MemberExpression) expressionNode() {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return "(" + me.Left.String() + "." + me.Member.String() + ")"
+}
+
+type EnumStatement struct {
+	Token    token.Token // The 'enum' Token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier // Payload field names, empty for plain tags
+}
+
+type MatchExpression struct {
+	Token   token.Token // The 'match' Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+/*
+	A MatchArm pattern is either the '_' wildcard, a variant name (i.e. Pending
+	or Status.Pending), a variant with bindings for its payload (i.e. Settled(x))
+	or any other expression, compared against the subject by value.
+*/
+type MatchArm struct {
+	Token   token.Token // The '=>' Token
+	Pattern Expression
+	Body    *BlockStatement
+}
+
+func (es *EnumStatement) statementNode() {}
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStatement) String() string {
+	variants := []string{}
+	for _, variant := range es.Variants {
+		variants = append(variants, variant.String())
+	}
+
+	return "enum " + es.Name.String() + " { " + strings.Join(variants, ", ") + " }"
+}
+
+func (ev *EnumVariant) String() string {
+	if len(ev.Fields) == 0 {
+		return ev.Name.String()
+	}
+
+	fields := []string{}
+	for _, field := range ev.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return ev.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+func (me *MatchExpression) expressionNode() {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out strings.Builder
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (ma *MatchArm) String() string {
+	return ma.Pattern.String() + " => " + ma.Body.String()
+}
+
+type ThrowStatement struct {
+	Token token.Token // The 'throw' Token
+	Value Expression
+}
+
+/*
+	A TryExpression evaluates Block and, if it fails, the Catch block with the
+	error bound to CatchParam (when given). Finally always runs last. Either
+	Catch or Finally may be missing, but not both.
+*/
+type TryExpression struct {
+	Token      token.Token // The 'try' Token
+	Block      *BlockStatement
+	CatchParam *Identifier
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+// A SpawnExpression runs Call on a goroutine of its own, evaluating to a task.
+type SpawnExpression struct {
+	Token token.Token // The 'spawn' Token
+	Call  *CallExpression
+}
+
+func (se *SpawnExpression) expressionNode() {}
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExpression) String() string { return "spawn " + se.Call.String() }
+
+type SelectExpression struct {
+	Token token.Token // The 'select' Token
+	Cases []*SelectCase
+}
+
+/*
+	A SelectCase is ready when its Operation, either recv(channel) or
+	send(channel, value), can go on without blocking. A nil Operation is the
+	'_' default case, taken when no other case is ready. Binding, if any, names
+	the value received.
+*/
+type SelectCase struct {
+	Token     token.Token // The '=>' Token
+	Binding   *Identifier
+	Operation *CallExpression
+	Body      *BlockStatement
+}
+
+func (se *SelectExpression) expressionNode() {}
+func (se *SelectExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectExpression) String() string {
+	cases := []string{}
+	for _, c := range se.Cases {
+		cases = append(cases, c.String())
+	}
+
+	return "select { " + strings.Join(cases, ", ") + " }"
+}
+
+func (sc *SelectCase) String() string {
+	var out strings.Builder
+
+	if sc.Binding != nil {
+		out.WriteString(sc.Binding.String() + " = ")
+	}
+
+	if sc.Operation != nil {
+		out.WriteString(sc.Operation.String())
+	} else {
+		out.WriteString("_")
+	}
+
+	out.WriteString(" => " + sc.Body.String())
+
+	return out.String()
+}
+
+// A YieldStatement hands Value to the consumer of the enclosing generator.
+type YieldStatement struct {
+	Token token.Token // The 'yield' Token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode() {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string {
+	return ys.TokenLiteral() + " " + ys.Value.String() + ";"
+}
+
+func (ts *ThrowStatement) statementNode() {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
+func (te *TryExpression) expressionNode() {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("try { ")
+	out.WriteString(te.Block.String())
+	out.WriteString(" }")
+
+	if te.Catch != nil {
+		out.WriteString(" catch ")
+		if te.CatchParam != nil {
+			out.WriteString("(" + te.CatchParam.String() + ") ")
+		}
+		out.WriteString("{ ")
+		out.WriteString(te.Catch.String())
+		out.WriteString(" }")
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally { ")
+		out.WriteString(te.Finally.String())
+		out.WriteString(" }")
+	}
+
+	return out.String()
+}
+
+type PropagateExpression struct {
+	Token token.Token // The '?' Token
+	Value Expression
+}
+
+func (pe *PropagateExpression) expressionNode() {}
+func (pe *PropagateExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PropagateExpression) String() string { return "(" + pe.Value.String() + "?)" }
+
+type SpreadElement struct {
+	Token token.Token // The '...' Token
+	Value Expression
+}
+
+func (se *SpreadElement) expressionNode() {}
+func (se *SpreadElement) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadElement) String() string { return "..." + se.Value.String() }
+
 func (hl *HashLiteral) expressionNode() {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 func (hl *HashLiteral) String() string {
@@ -136,8 +498,13 @@ func (hl *HashLiteral) String() string {
 
 	pairs := []string{}
 
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String() + ":" + value.String())
+	for _, pair := range hl.Pairs {
+		if pair.Value == nil {
+			pairs = append(pairs, pair.Key.String())
+			continue
+		}
+
+		pairs = append(pairs, pair.Key.String() + ":" + pair.Value.String())
 	}
 
 	out.WriteString("{ ")
@@ -210,7 +577,7 @@ func (fl *FunctionLiteral) String() string {
 	params := []string{}
 
 	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+		params = append(params, p.Declaration())
 	}
 
 	out.WriteString(fl.TokenLiteral())
@@ -218,6 +585,10 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
 
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
+
 	out.WriteString("{ ")
 	out.WriteString(fl.Body.String())
 	out.WriteString("}")
@@ -320,7 +691,7 @@ func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 func (ls *LetStatement) String() string {
 	var output bytes.Buffer
 
-	output.WriteString(ls.TokenLiteral() + " " + ls.Name.String() + " = " )
+	output.WriteString(ls.TokenLiteral() + " " + ls.Name.Declaration() + " = " )
 
 	if ls.Value != nil {
 		output.WriteString(ls.Value.String())
@@ -335,6 +706,15 @@ func (i *Identifier) expressionNode() {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string { return i.Value }
 
+// Renders the identifier along with its type annotation, if any (i.e. x: int).
+func (i *Identifier) Declaration() string {
+	if i.Type == nil {
+		return i.Value
+	}
+
+	return i.Value + ": " + i.Type.String()
+}
+
 func (p *Program) String() string {
 	var output bytes.Buffer
 
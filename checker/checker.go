@@ -0,0 +1,609 @@
+package checker
+
+import (
+	"dux/ast"
+	"dux/token"
+	"fmt"
+)
+
+/*
+	The checker is a gradual, static type checking pass over an *ast.Program. It
+	infers the type of expressions where it can and reports mismatches against
+	type annotations and operator operands, before the program ever runs.
+
+	Anything it can't infer is typed any, which is compatible with every other
+	type, so unannotated code is only reported when both sides of a mistake are
+	known for sure (i.e. "5" + 3).
+*/
+
+type Type struct {
+	Name       string
+	Parameters []*Type // Signature of fn types, nil when unknown
+	Return     *Type
+}
+
+func (t *Type) String() string { return t.Name }
+
+var (
+	Any    = &Type{Name: "any"}
+	Int    = &Type{Name: "int"}
+	String = &Type{Name: "string"}
+	Bool   = &Type{Name: "bool"}
+	Nil    = &Type{Name: "nil"}
+	Array  = &Type{Name: "array"}
+	Hash   = &Type{Name: "hash"}
+	Range  = &Type{Name: "range"}
+	Fn     = &Type{Name: "fn"}
+	Result = &Type{Name: "Result"}
+)
+
+var builtinTypes = map[string]*Type{
+	"any":    Any,
+	"int":    Int,
+	"string": String,
+	"bool":   Bool,
+	"nil":    Nil,
+	"array":  Array,
+	"hash":   Hash,
+	"range":  Range,
+	"fn":     Fn,
+}
+
+// Return types of the builtin functions, used when they aren't shadowed.
+var builtinReturns = map[string]*Type{
+	"len":      Int,
+	"type":     String,
+	"to_array": Array,
+	"tail":     Array,
+	"head":     Array,
+	"push":     Array,
+	"sort":     Array,
+	"contains": Bool,
+	"puts":     Nil,
+	"print":    Nil,
+	"eprint":   Nil,
+	"ok":       Result,
+	"err":      Result,
+	"is_ok":    Bool,
+	"is_err":   Bool,
+}
+
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message) }
+
+type scope struct {
+	types map[string]*Type
+	outer *scope
+}
+
+func (s *scope) get(name string) (*Type, bool) {
+	t, ok := s.types[name]
+	if !ok && s.outer != nil {
+		t, ok = s.outer.get(name)
+	}
+
+	return t, ok
+}
+
+/*
+	Tracks the function being checked: its declared return type (nil when not
+	annotated) and the types it was seen returning, used to infer the return
+	type of unannotated functions.
+*/
+type function struct {
+	declared *Type
+	returns  []*Type
+}
+
+type checker struct {
+	errors    []*Error
+	scope     *scope
+	functions []*function
+	named     map[string]bool // Struct and enum names, usable as types
+}
+
+/*
+	Checks program and returns every type error found, in source order. An
+	empty result means the program is well typed as far as the checker knows.
+*/
+func Check(program *ast.Program) []*Error {
+	c := &checker{scope: &scope{types: map[string]*Type{}}, named: map[string]bool{"Result": true}}
+
+	// Top level structs and enums can be named by annotations before they're declared
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.StructStatement:
+			c.named[stmt.Name.Value] = true
+		case *ast.EnumStatement:
+			c.named[stmt.Name.Value] = true
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		c.check(stmt)
+	}
+
+	return c.errors
+}
+
+func (c *checker) errorf(tok token.Token, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *checker) pushScope() { c.scope = &scope{types: map[string]*Type{}, outer: c.scope} }
+func (c *checker) popScope() { c.scope = c.scope.outer }
+
+func (c *checker) declare(name string, t *Type) { c.scope.types[name] = t }
+
+/*
+	Returns the type an annotation names. Names that are neither builtin types
+	nor structs or enums (i.e. decimal) are typed any, the way unannotated
+	code is.
+*/
+func (c *checker) resolve(annotation *ast.TypeAnnotation) *Type {
+	if annotation == nil {
+		return Any
+	}
+
+	if t, ok := builtinTypes[annotation.Name]; ok {
+		return t
+	}
+
+	// Structs and enums are compared by name
+	if c.named[annotation.Name] {
+		return &Type{Name: annotation.Name}
+	}
+
+	return Any
+}
+
+func assignable(from, to *Type) bool {
+	return from == Any || to == Any || from.Name == to.Name
+}
+
+func known(t *Type) bool { return t != Any }
+
+func (c *checker) check(node ast.Node) *Type {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		if node.Expression == nil { return Any }
+		return c.check(node.Expression)
+	case *ast.LetStatement:
+		c.checkLetStatement(node)
+		return Nil
+	case *ast.ReturnStatement:
+		c.checkReturn(node.ReturnValue, c.check(node.ReturnValue))
+		return Any
+	case *ast.BlockStatement:
+		return c.checkBlock(node)
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			c.check(element)
+		}
+		return Array
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			c.check(pair.Key)
+			if pair.Value != nil { c.check(pair.Value) }
+		}
+		return Hash
+	case *ast.Identifier:
+		if node.Value == "nil" { return Nil }
+
+		if t, ok := c.scope.get(node.Value); ok {
+			return t
+		}
+		return Any
+	case *ast.PrefixExpression:
+		return c.checkPrefixExpression(node)
+	case *ast.InfixExpression:
+		return c.checkInfixExpression(node)
+	case *ast.IfExpression:
+		return c.checkIfExpression(node)
+	case *ast.FunctionLiteral:
+		return c.checkFunction(node, nil)
+	case *ast.CallExpression:
+		return c.checkCallExpression(node)
+	case *ast.RangeExpression:
+		for _, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+			if bound == nil { continue }
+
+			if t := c.check(bound); !assignable(t, Int) {
+				c.errorf(tokenOf(bound), "range bounds must be int, got %s", t)
+			}
+		}
+		return Range
+	case *ast.ForExpression:
+		iterable := c.check(node.Iterable)
+
+		element := Any
+		if iterable == Range { element = Int }
+
+		c.pushScope()
+		c.declare(node.Variable.Value, element)
+		c.check(node.Body)
+		c.popScope()
+
+		return Nil
+	case *ast.IndexExpresssion:
+		c.check(node.Left)
+		c.check(node.Index)
+		return Any
+	case *ast.MemberExpression:
+		c.check(node.Left)
+		return Any
+	case *ast.SpreadElement:
+		c.check(node.Value)
+		return Any
+	case *ast.PropagateExpression:
+		c.check(node.Value)
+		return Any
+	case *ast.StructStatement:
+		c.named[node.Name.Value] = true
+
+		instance := &Type{Name: node.Name.Value}
+		fields := make([]*Type, len(node.Fields))
+		for i := range fields {
+			fields[i] = Any
+		}
+
+		c.declare(node.Name.Value, &Type{Name: "struct", Parameters: fields, Return: instance})
+
+		for _, method := range node.Methods {
+			c.checkFunction(method.Function, instance)
+		}
+		return Nil
+	case *ast.EnumStatement:
+		c.named[node.Name.Value] = true
+		c.declare(node.Name.Value, &Type{Name: "enum"})
+		return Nil
+	case *ast.MatchExpression:
+		c.check(node.Subject)
+
+		for _, arm := range node.Arms {
+			c.pushScope()
+			declarePatternBindings(c, arm.Pattern)
+			c.check(arm.Body)
+			c.popScope()
+		}
+		return Any
+	case *ast.TryExpression:
+		c.check(node.Block)
+
+		if node.Catch != nil {
+			c.pushScope()
+			if node.CatchParam != nil {
+				c.declare(node.CatchParam.Value, Any)
+			}
+			c.check(node.Catch)
+			c.popScope()
+		}
+
+		if node.Finally != nil {
+			c.check(node.Finally)
+		}
+		return Any
+	case *ast.ThrowStatement:
+		c.check(node.Value)
+		return Any
+	case *ast.SpawnExpression:
+		c.check(node.Call)
+		return Any
+	case *ast.SelectExpression:
+		for _, selectCase := range node.Cases {
+			if selectCase.Operation != nil {
+				for _, arg := range selectCase.Operation.Arguments {
+					c.check(arg)
+				}
+			}
+
+			c.pushScope()
+			if selectCase.Binding != nil {
+				c.declare(selectCase.Binding.Value, Any)
+			}
+			c.check(selectCase.Body)
+			c.popScope()
+		}
+		return Any
+	case *ast.YieldStatement:
+		c.check(node.Value)
+		return Nil
+	}
+
+	return Any
+}
+
+func (c *checker) checkLetStatement(node *ast.LetStatement) {
+	var value *Type
+	if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+		// Declared before checking the body, so recursive calls are checked too
+		c.declare(node.Name.Value, c.signature(fn))
+		value = c.checkFunction(fn, nil)
+	} else {
+		value = c.check(node.Value)
+	}
+
+	if node.Name.Type == nil {
+		c.declare(node.Name.Value, value)
+		return
+	}
+
+	declared := c.resolve(node.Name.Type)
+	if !assignable(value, declared) {
+		c.errorf(tokenOf(node.Value), "cannot assign %s to %s of type %s", value, node.Name.Value, declared)
+	}
+
+	c.declare(node.Name.Value, declared)
+}
+
+func (c *checker) checkBlock(block *ast.BlockStatement) *Type {
+	result := Any
+
+	for _, stmt := range block.Statements {
+		result = c.check(stmt)
+	}
+
+	return result
+}
+
+func (c *checker) checkReturn(value ast.Expression, t *Type) {
+	if len(c.functions) == 0 { return }
+
+	fn := c.functions[len(c.functions)-1]
+	fn.returns = append(fn.returns, t)
+
+	if fn.declared != nil && !assignable(t, fn.declared) {
+		c.errorf(tokenOf(value), "cannot return %s from function returning %s", t, fn.declared)
+	}
+}
+
+func (c *checker) signature(fn *ast.FunctionLiteral) *Type {
+	params := make([]*Type, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = c.resolve(param.Type)
+	}
+
+	return &Type{Name: "fn", Parameters: params, Return: c.resolve(fn.ReturnType)}
+}
+
+/*
+	Checks a function body against its annotations and returns its fn type.
+	Unannotated functions get the return type their body always evaluates to,
+	if there's a single one. self, when given, is the type of the first
+	parameter of struct methods.
+*/
+func (c *checker) checkFunction(fn *ast.FunctionLiteral, self *Type) *Type {
+	t := c.signature(fn)
+
+	current := &function{}
+	if fn.ReturnType != nil {
+		current.declared = t.Return
+	}
+
+	c.pushScope()
+	c.functions = append(c.functions, current)
+
+	for i, param := range fn.Parameters {
+		paramType := t.Parameters[i]
+		if i == 0 && self != nil && param.Type == nil {
+			paramType = self
+		}
+
+		c.declare(param.Value, paramType)
+	}
+
+	// The last expression of the body is its implicit return value
+	statements := fn.Body.Statements
+	for i, stmt := range statements {
+		result := c.check(stmt)
+
+		if es, ok := stmt.(*ast.ExpressionStatement); ok && i == len(statements)-1 && es.Expression != nil {
+			c.checkReturn(es.Expression, result)
+		}
+	}
+
+	c.functions = c.functions[:len(c.functions)-1]
+	c.popScope()
+
+	if fn.ReturnType == nil && !fn.IsGenerator {
+		t.Return = commonType(current.returns)
+	}
+
+	return t
+}
+
+func commonType(types []*Type) *Type {
+	if len(types) == 0 { return Any }
+
+	for _, t := range types[1:] {
+		if t.Name != types[0].Name { return Any }
+	}
+
+	return types[0]
+}
+
+func (c *checker) checkPrefixExpression(node *ast.PrefixExpression) *Type {
+	right := c.check(node.Right)
+
+	switch node.Operator {
+	case "!":
+		return Bool
+	case "-":
+		if !assignable(right, Int) {
+			c.errorf(node.Token, "unknown operator: -%s", right)
+		}
+		return Int
+	}
+
+	return Any
+}
+
+func (c *checker) checkInfixExpression(node *ast.InfixExpression) *Type {
+	left := c.check(node.Left)
+	right := c.check(node.Right)
+
+	switch node.Operator {
+	case "==", "!=", "in":
+		return Bool
+	}
+
+	if !known(left) || !known(right) {
+		switch node.Operator {
+		case "<", ">", "<=", ">=":
+			return Bool
+		case "-", "/":
+			return Int
+		}
+		return Any
+	}
+
+	switch {
+	case left == Int && right == Int:
+		switch node.Operator {
+		case "+", "-", "*", "/":
+			return Int
+		case "<", ">", "<=", ">=":
+			return Bool
+		}
+	case left == String && right == String:
+		switch node.Operator {
+		case "+":
+			return String
+		case "<", ">", "<=", ">=":
+			return Bool
+		}
+	case node.Operator == "*" && (left == String && right == Int || left == Int && right == String):
+		return String
+	}
+
+	if left.Name != right.Name {
+		c.errorf(node.Token, "type mismatch: %s %s %s", left, node.Operator, right)
+	} else {
+		c.errorf(node.Token, "unknown operator: %s %s %s", left, node.Operator, right)
+	}
+
+	return Any
+}
+
+func (c *checker) checkIfExpression(node *ast.IfExpression) *Type {
+	c.check(node.Condition)
+
+	consequence := c.check(node.Consequence)
+	if node.Alternative == nil {
+		return Any
+	}
+
+	alternative := c.check(node.Alternative)
+
+	return commonType([]*Type{consequence, alternative})
+}
+
+func (c *checker) checkCallExpression(node *ast.CallExpression) *Type {
+	callee := c.check(node.Function)
+
+	args := make([]*Type, len(node.Arguments))
+	spread := false
+	for i, arg := range node.Arguments {
+		args[i] = c.check(arg)
+
+		if _, ok := arg.(*ast.SpreadElement); ok {
+			spread = true
+		}
+	}
+
+	name := node.Function.String()
+
+	if ident, ok := node.Function.(*ast.Identifier); ok && callee == Any {
+		if _, shadowed := c.scope.get(ident.Value); !shadowed {
+			if t, ok := builtinReturns[ident.Value]; ok {
+				return t
+			}
+		}
+	}
+
+	if callee.Parameters == nil || spread {
+		if callee.Return != nil { return callee.Return }
+		return Any
+	}
+
+	if len(args) != len(callee.Parameters) {
+		c.errorf(node.Token, "wrong number of arguments to %s. got=%d, want=%d", name, len(args), len(callee.Parameters))
+		return callee.Return
+	}
+
+	for i, arg := range args {
+		if !assignable(arg, callee.Parameters[i]) {
+			c.errorf(tokenOf(node.Arguments[i]), "cannot use %s as %s in argument %d to %s", arg, callee.Parameters[i], i+1, name)
+		}
+	}
+
+	return callee.Return
+}
+
+func declarePatternBindings(c *checker, pattern ast.Expression) {
+	call, ok := pattern.(*ast.CallExpression)
+	if !ok { return }
+
+	for _, arg := range call.Arguments {
+		if ident, ok := arg.(*ast.Identifier); ok {
+			c.declare(ident.Value, Any)
+		}
+	}
+}
+
+// Returns the token an error about node should point at.
+func tokenOf(node ast.Node) token.Token {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return node.Token
+	case *ast.IntegerLiteral:
+		return node.Token
+	case *ast.StringLiteral:
+		return node.Token
+	case *ast.Boolean:
+		return node.Token
+	case *ast.ArrayLiteral:
+		return node.Token
+	case *ast.HashLiteral:
+		return node.Token
+	case *ast.FunctionLiteral:
+		return node.Token
+	case *ast.PrefixExpression:
+		return node.Token
+	case *ast.InfixExpression:
+		return tokenOf(node.Left)
+	case *ast.CallExpression:
+		return tokenOf(node.Function)
+	case *ast.IndexExpresssion:
+		return tokenOf(node.Left)
+	case *ast.MemberExpression:
+		return tokenOf(node.Left)
+	case *ast.PropagateExpression:
+		return tokenOf(node.Value)
+	case *ast.RangeExpression:
+		return tokenOf(node.Start)
+	case *ast.IfExpression:
+		return node.Token
+	case *ast.MatchExpression:
+		return node.Token
+	case *ast.TryExpression:
+		return node.Token
+	case *ast.ForExpression:
+		return node.Token
+	case *ast.SpreadElement:
+		return node.Token
+	}
+
+	return token.Token{}
+}
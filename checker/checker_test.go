@@ -0,0 +1,80 @@
+package checker
+
+import (
+	"dux/lexer"
+	"dux/parser"
+	"testing"
+)
+
+func TestCheckWellTypedPrograms(t *testing.T) {
+	tests := []string{
+		`let x: int = 5; x + 10`,
+		`let name: string = "dux"; name + "!"`,
+		`let add = fn(x: int, y: int) -> int { x + y }; add(1, 2) * 3`,
+		`let scale = fn(x: int, y: decimal) -> decimal { y }; scale(1, rate)`,
+		`let scale = fn(x: int, y: Money) -> Money { y }; struct Money { cents } scale(1, Money(5))`,
+		`let x: strng = "a"; x + 1`,
+		`let f = fn(x) { x }; f("a") + f(1)`,
+		`let fact = fn(n: int) -> int { if (n < 2) { return 1; } n * fact(n - 1) }; fact(5)`,
+		`let x = unknown(); x + 1; x + "a"`,
+		`let n: int = len([1, 2]); "a" * n`,
+		`struct Account { id, balance fn deposit(self, amount: int) -> int { self.balance + amount } } let a: Account = Account(1, 2)`,
+		`for (i in 0..10) { i * 2 }`,
+		`let len = fn(x) { "custom" }; len([]) + "!"`,
+		`let g = fn() { yield 1; 2 }; g() + "a"`,
+		`let first: bool = "a" < "b"; let last: bool = "a" >= "b"`,
+	}
+
+	for _, input := range tests {
+		errors := check(t, input)
+
+		if len(errors) != 0 {
+			t.Errorf("unexpected type errors for %q: %v", input, errors)
+		}
+	}
+}
+
+func TestCheckTypeErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{`"5" + 3`, "1:5: type mismatch: string + int"},
+		{`true + false`, "1:6: unknown operator: bool + bool"},
+		{`-"a"`, "1:1: unknown operator: -string"},
+		{`let x: int = "five";`, `1:14: cannot assign string to x of type int`},
+		{`let add = fn(x: int, y: int) { x + y }; add(1)`, "1:44: wrong number of arguments to add. got=1, want=2"},
+		{`let add = fn(x: int, y: int) { x + y };
+add(1, "2")`, "2:8: cannot use string as int in argument 2 to add"},
+		{`let f = fn(x: int) -> int { "x" };`, `1:29: cannot return string from function returning int`},
+		{`let f = fn(x: int) -> string { return x * 2; };`, `1:39: cannot return int from function returning string`},
+		{`let f = fn() { 1 }; f() + "a"`, "1:25: type mismatch: int + string"},
+		{`let x: string = len("abc");`, `1:17: cannot assign int to x of type string`},
+		{`0.."10"`, `1:4: range bounds must be int, got string`},
+		{`let f = fn(x) { if (x) { 1 } else { 2 } }; let s: string = f(true);`, `1:60: cannot assign int to s of type string`},
+	}
+
+	for _, tc := range tests {
+		errors := check(t, tc.input)
+
+		if len(errors) != 1 {
+			t.Errorf("wrong number of type errors for %q. got=%v, want=1", tc.input, errors)
+			continue
+		}
+
+		if errors[0].Error() != tc.expected {
+			t.Errorf("wrong type error for %q. want=%q, got=%q", tc.input, tc.expected, errors[0].Error())
+		}
+	}
+}
+
+func check(t *testing.T, input string) []*Error {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return Check(program)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"dux/checker"
+	"dux/compiler"
+	"dux/evaluator"
+	"dux/lexer"
+	"dux/object"
+	"dux/optimizer"
+	"dux/parser"
+	"dux/repl"
+	"dux/vm"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	args, opts := parseOptions(os.Args[1:])
+
+	if len(args) == 0 {
+		user, err := user.Current()
+
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("Welcome %s. Dux Language Interpreter!\n", user.Username)
+		fmt.Printf("You can evalute Dux commands here\n");
+
+		repl.Start(os.Stdin, os.Stdout)
+	} else if args[0] == "check" && len(args) == 2 {
+		os.Exit(check(args[1]))
+	} else if opts.engine != "eval" && opts.engine != "vm" {
+		fmt.Printf("Error: unknown engine %s, want eval or vm\n", opts.engine)
+		os.Exit(1)
+	} else if opts.errors != "text" && opts.errors != "json" {
+		fmt.Printf("Error: unknown error format %s, want text or json\n", opts.errors)
+		os.Exit(1)
+	} else {
+		absp, err := filepath.Abs(args[0])
+		if err != nil { fmt.Println("Error:", err); os.Exit(1) }
+
+		content, err := os.ReadFile(absp)
+		if err != nil { fmt.Println("Error:", err); os.Exit(1) }
+
+		l := lexer.New(string(content))
+		p := parser.New(l)
+		program := p.ParseProgram()
+		env := object.NewEnvironment()
+		env.SetStrictIntegers(opts.strict)
+
+		if len(p.Errors()) != 0 {
+			for _, msg := range p.Errors() {
+				if opts.errors == "json" {
+					printJSON(jsonError{Kind: "SyntaxError", Code: "syntax_error", Message: msg, File: args[0]})
+				} else {
+					fmt.Printf("%s: %s\n", args[0], msg)
+				}
+			}
+			os.Exit(1)
+		}
+
+		program = optimizer.Optimize(program)
+
+		if opts.dumpAST {
+			for _, stmt := range program.Statements {
+				fmt.Println(stmt.String())
+			}
+			return
+		}
+
+		if errors := evaluator.Resolve(program, env); len(errors) != 0 {
+			for _, err := range errors {
+				if opts.errors == "json" {
+					printJSON(jsonError{Kind: "NameError", Code: "identifier_not_found", Message: err.Message, File: args[0], Line: err.Line, Column: err.Column})
+				} else {
+					fmt.Printf("%s:%s\n", args[0], err)
+				}
+			}
+			os.Exit(1)
+		}
+
+		var evaluated object.Object
+		if opts.engine == "vm" {
+			evaluated = vm.Run(compiler.Compile(program), env)
+		} else {
+			evaluated = evaluator.Eval(program, env)
+		}
+
+		failure, failed := evaluated.(*object.Error)
+		if failed && opts.errors == "json" {
+			printJSON(toJSON(failure, args[0]))
+			os.Exit(1)
+		}
+
+		if failed {
+			fmt.Print(failure.Traceback())
+			fmt.Print(failure.Inspect())
+			os.Exit(1)
+		}
+
+		fmt.Print(evaluated.Inspect())
+	}
+}
+
+type options struct {
+	engine  string // Engine files are run with: eval, the default, or vm
+	dumpAST bool   // Print the optimized program instead of running it
+	errors  string // Format errors are printed in: text, the default, or json
+	strict  bool   // Fail on integers overflowing 64 bits instead of promoting them
+}
+
+// Removes the --engine=eval|vm, --errors=text|json, --strict-integers and --dump-ast options from args, returning them parsed.
+func parseOptions(args []string) ([]string, options) {
+	opts := options{engine: "eval", errors: "text"}
+	rest := []string{}
+
+	for _, arg := range args {
+		if name, ok := strings.CutPrefix(arg, "--engine="); ok {
+			opts.engine = name
+		} else if format, ok := strings.CutPrefix(arg, "--errors="); ok {
+			opts.errors = format
+		} else if arg == "--dump-ast" {
+			opts.dumpAST = true
+		} else if arg == "--strict-integers" {
+			opts.strict = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+
+	return rest, opts
+}
+
+// Type checks the file at path without running it, returning the exit code.
+func check(path string) int {
+	content, err := os.ReadFile(path)
+	if err != nil { fmt.Println("Error:", err); return 1 }
+
+	p := parser.New(lexer.New(string(content)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Printf("%s: %s\n", path, msg)
+		}
+		return 1
+	}
+
+	errors := checker.Check(program)
+	for _, err := range errors {
+		fmt.Printf("%s:%s\n", path, err)
+	}
+
+	if len(errors) != 0 { return 1 }
+
+	return 0
+}
+
+/*
+	Errors are printed as one JSON object per line with --errors=json, the
+	values they're about rendered as dx would, and the calls they unwound
+	innermost first.
+*/
+type jsonError struct {
+	Kind    string      `json:"kind"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	File    string      `json:"file"`
+	Line    int         `json:"line"`
+	Column  int         `json:"column"`
+	Values  []string    `json:"values,omitempty"`
+	Trace   []jsonFrame `json:"trace,omitempty"`
+}
+
+type jsonFrame struct {
+	Function string `json:"function"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+func toJSON(err *object.Error, file string) jsonError {
+	converted := jsonError{Kind: err.Kind, Code: err.Code, Message: err.Message, File: file, Line: err.Line, Column: err.Column}
+
+	for _, value := range err.Values {
+		converted.Values = append(converted.Values, value.Inspect())
+	}
+
+	for _, frame := range err.Trace {
+		converted.Trace = append(converted.Trace, jsonFrame{Function: frame.Function, Line: frame.Line, Column: frame.Column})
+	}
+
+	return converted
+}
+
+func printJSON(err jsonError) {
+	encoded, _ := json.Marshal(err)
+	fmt.Println(string(encoded))
+}
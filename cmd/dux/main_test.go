@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The test binary runs main instead of the tests when asked to by runCLI.
+func TestMain(m *testing.M) {
+	if os.Getenv("DUX_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct{
+		source string
+		args   []string
+		status int
+		output string
+	}{
+		{"1 + 2", nil, 0, "3"},
+		{"1 + 2", []string{"--engine=vm"}, 0, "3"},
+		{"let f = fn() { 1 + true };\nf()", nil, 1, "Traceback (most recent call last):\n  at 2:2, in <program>\n  at 1:18, in f\nERROR type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn() { 1 + true };\nf()", []string{"--engine=vm"}, 1, "ERROR type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn() { 1 + true };\nf()", []string{"--errors=json"}, 1, `"kind":"TypeError"`},
+		{`throw "boom"`, nil, 1, "ERROR boom"},
+		{"let x = ;", nil, 1, "no prefix parse function"},
+		{"missing + 1", nil, 1, "identifier not found: missing"},
+	}
+
+	for _, tc := range tests {
+		path := filepath.Join(t.TempDir(), "program.dx")
+		if err := os.WriteFile(path, []byte(tc.source), 0o644); err != nil { t.Fatal(err) }
+
+		status, output := runCLI(t, append(tc.args, path)...)
+
+		if status != tc.status {
+			t.Errorf("wrong exit status for %q %v. want=%d, got=%d", tc.source, tc.args, tc.status, status)
+		}
+
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("wrong output for %q %v. want it to contain %q, got=%q", tc.source, tc.args, tc.output, output)
+		}
+	}
+
+	if status, _ := runCLI(t, filepath.Join(t.TempDir(), "missing.dx")); status != 1 {
+		t.Errorf("wrong exit status for a missing file. want=1, got=%d", status)
+	}
+}
+
+// Runs the command with args, returning its exit status and what it printed.
+func runCLI(t *testing.T, args ...string) (int, string) {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "DUX_RUN_MAIN=1")
+
+	output, err := cmd.CombinedOutput()
+
+	var exit *exec.ExitError
+	if errors.As(err, &exit) { return exit.ExitCode(), string(output) }
+	if err != nil { t.Fatalf("running the command failed: %s", err) }
+
+	return 0, string(output)
+}
@@ -0,0 +1,184 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+/*
+	Instructions are a flat sequence of opcodes, each followed by its operands
+	encoded in big endian, with the widths given by the opcode's definition.
+*/
+type Instructions []byte
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota // Pushes constant [index]
+	OpNone                   // Pushes the absence of a value, left by statements such as let
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpEqual
+	OpNotEqual
+	OpGreater
+	OpLess
+	OpGreaterEqual
+	OpLessEqual
+	OpIn
+	OpMinus
+	OpBang
+
+	OpJump          // Jumps to [offset]
+	OpJumpNotTruthy // Pops the condition and jumps to [offset] if it's falsy
+
+	OpGetName // Pushes the value of identifier node [index]
+	OpSetName // Pops a value and binds it to the name identifier node [index] declares
+
+	OpArray  // Pops [count] elements into an array
+	OpIndex  // Pops an index and the value it indexes
+	OpMember // Pops a value and pushes its member named [index]
+
+	OpFunction    // Pushes a closure of function literal node [index]
+	OpCall        // Calls the function below its [count] arguments
+	OpTailCall    // Same as OpCall, replacing the calling frame
+	OpReturnValue // Returns the value on top of the stack from the frame
+
+	OpEval // Hands node [index] over to the evaluator
+)
+
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{4}},
+	OpNone:     {"OpNone", []int{}},
+	OpNil:      {"OpNil", []int{}},
+	OpTrue:     {"OpTrue", []int{}},
+	OpFalse:    {"OpFalse", []int{}},
+	OpPop:      {"OpPop", []int{}},
+
+	OpAdd:      {"OpAdd", []int{}},
+	OpSub:      {"OpSub", []int{}},
+	OpMul:      {"OpMul", []int{}},
+	OpDiv:      {"OpDiv", []int{}},
+	OpEqual:    {"OpEqual", []int{}},
+	OpNotEqual: {"OpNotEqual", []int{}},
+	OpGreater:  {"OpGreater", []int{}},
+	OpLess:     {"OpLess", []int{}},
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+	OpLessEqual:    {"OpLessEqual", []int{}},
+	OpIn:       {"OpIn", []int{}},
+	OpMinus:    {"OpMinus", []int{}},
+	OpBang:     {"OpBang", []int{}},
+
+	OpJump:          {"OpJump", []int{4}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{4}},
+
+	OpGetName: {"OpGetName", []int{4}},
+	OpSetName: {"OpSetName", []int{4}},
+
+	OpArray:  {"OpArray", []int{4}},
+	OpIndex:  {"OpIndex", []int{}},
+	OpMember: {"OpMember", []int{4}},
+
+	OpFunction:    {"OpFunction", []int{4}},
+	OpCall:        {"OpCall", []int{1}},
+	OpTailCall:    {"OpTailCall", []int{1}},
+	OpReturnValue: {"OpReturnValue", []int{}},
+
+	OpEval: {"OpEval", []int{4}},
+}
+
+func Lookup(op Opcode) (*Definition, error) {
+	def, ok := definitions[op]
+	if !ok { return nil, fmt.Errorf("opcode %d undefined", op) }
+
+	return def, nil
+}
+
+// Encodes op and its operands into an instruction.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok { return []byte{} }
+
+	length := 1
+	for _, width := range def.OperandWidths {
+		length += width
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, operand := range operands {
+		width := def.OperandWidths[i]
+
+		switch width {
+		case 4:
+			binary.BigEndian.PutUint32(instruction[offset:], uint32(operand))
+		case 1:
+			instruction[offset] = byte(operand)
+		}
+
+		offset += width
+	}
+
+	return instruction
+}
+
+// Decodes the operands of an instruction, returning them with their total width.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 4:
+			operands[i] = int(ReadUint32(ins[offset:]))
+		case 1:
+			operands[i] = int(ins[offset])
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint32(ins Instructions) uint32 { return binary.BigEndian.Uint32(ins) }
+
+// Disassembles the instructions, one per line prefixed by its offset.
+func (ins Instructions) String() string {
+	var out strings.Builder
+
+	for i := 0; i < len(ins); {
+		def, err := Lookup(Opcode(ins[i]))
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		fmt.Fprintf(&out, "%04d %s", i, def.Name)
+		for _, operand := range operands {
+			fmt.Fprintf(&out, " %d", operand)
+		}
+		out.WriteString("\n")
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
@@ -0,0 +1,316 @@
+package compiler
+
+import (
+	"dux/ast"
+	"dux/evaluator"
+	"dux/object"
+	"dux/token"
+	"sync"
+)
+
+/*
+	The compiler lowers an AST to bytecode for the vm. Literals, names,
+	operators, conditionals, functions and calls, which make up the hot paths
+	of most programs, are compiled to instructions. Every other node is kept
+	in the bytecode as is and handed over to the evaluator when reached, so
+	the whole language runs the same way on both engines.
+
+	Variables live in an *object.Environment, like they do in the evaluator,
+	which is what lets both engines share them. They're found the same way
+	too, through the bindings the resolver gave identifiers, if any.
+*/
+type Bytecode struct {
+	Instructions Instructions
+	Constants    []object.Object
+	Names        []string            // Names of the members accessed
+	Nodes        []ast.Node          // Identifiers, function literals and nodes left to the evaluator
+	Positions    map[int]token.Token // Token of every instruction that may fail, by offset
+
+	functions *sync.Map // Bytecode of the function bodies called, shared with theirs
+}
+
+type compiler struct {
+	bytecode  *Bytecode
+	integers  map[int64]int
+	strings   map[string]int
+	nameIndex map[string]int
+}
+
+// Compiles a program, whose bytecode returns the value of its last statement.
+func Compile(program *ast.Program) *Bytecode {
+	c := newCompiler()
+
+	c.compileStatements(program.Statements)
+	c.emit(OpReturnValue)
+
+	return c.bytecode
+}
+
+// Compiles the body of a function, whose bytecode returns the function's value.
+func CompileFunction(body *ast.BlockStatement) *Bytecode {
+	c := newCompiler()
+
+	c.compileBlock(body)
+	c.emit(OpReturnValue)
+
+	return c.bytecode
+}
+
+/*
+	Returns the bytecode of a function body called from b, compiling it the
+	first time. It's kept for as long as b is, so functions are compiled once
+	per program whatever the number of calls or closures.
+*/
+func (b *Bytecode) Function(body *ast.BlockStatement) *Bytecode {
+	if code, ok := b.functions.Load(body); ok { return code.(*Bytecode) }
+
+	code := CompileFunction(body)
+	code.functions = b.functions
+
+	stored, _ := b.functions.LoadOrStore(body, code)
+
+	return stored.(*Bytecode)
+}
+
+func newCompiler() *compiler {
+	return &compiler{
+		bytecode:  &Bytecode{Positions: map[int]token.Token{}, functions: &sync.Map{}},
+		integers:  map[int64]int{},
+		strings:   map[string]int{},
+		nameIndex: map[string]int{},
+	}
+}
+
+func (c *compiler) emit(op Opcode, operands ...int) int {
+	position := len(c.bytecode.Instructions)
+	c.bytecode.Instructions = append(c.bytecode.Instructions, Make(op, operands...)...)
+
+	return position
+}
+
+// Emits an instruction that may fail, recording the token errors point at.
+func (c *compiler) emitAt(tok token.Token, op Opcode, operands ...int) int {
+	position := c.emit(op, operands...)
+	c.bytecode.Positions[position] = tok
+
+	return position
+}
+
+// Sets the operand of the jump at position once its target is known.
+func (c *compiler) patchJump(position int) {
+	target := len(c.bytecode.Instructions)
+	copy(c.bytecode.Instructions[position+1:], Make(OpJump, target)[1:])
+}
+
+func (c *compiler) constant(obj object.Object) int {
+	c.bytecode.Constants = append(c.bytecode.Constants, obj)
+	return len(c.bytecode.Constants) - 1
+}
+
+func (c *compiler) integer(value int64) int {
+	if index, ok := c.integers[value]; ok { return index }
+
+	// The evaluator relies on the literal 0 being the ZERO singleton
+	var obj object.Object = evaluator.ZERO
+	if value != 0 {
+		obj = &object.Integer{Value: value}
+	}
+
+	c.integers[value] = c.constant(obj)
+
+	return c.integers[value]
+}
+
+func (c *compiler) string(value string) int {
+	if index, ok := c.strings[value]; ok { return index }
+
+	c.strings[value] = c.constant(&object.String{Value: value})
+
+	return c.strings[value]
+}
+
+func (c *compiler) name(name string) int {
+	if index, ok := c.nameIndex[name]; ok { return index }
+
+	c.bytecode.Names = append(c.bytecode.Names, name)
+	c.nameIndex[name] = len(c.bytecode.Names) - 1
+
+	return c.nameIndex[name]
+}
+
+func (c *compiler) node(node ast.Node) int {
+	c.bytecode.Nodes = append(c.bytecode.Nodes, node)
+	return len(c.bytecode.Nodes) - 1
+}
+
+/*
+	Compiles statements so that they leave the value of the last one on the
+	stack, like a block evaluates to its last statement.
+*/
+func (c *compiler) compileStatements(statements []ast.Statement) {
+	if len(statements) == 0 {
+		c.emit(OpNone)
+		return
+	}
+
+	for i, stmt := range statements {
+		pushed := c.compileStatement(stmt)
+		last := i == len(statements)-1
+
+		if pushed && !last {
+			c.emit(OpPop)
+		} else if !pushed && last {
+			c.emit(OpNone)
+		}
+	}
+}
+
+func (c *compiler) compileBlock(block *ast.BlockStatement) {
+	if block == nil {
+		c.emit(OpNone)
+		return
+	}
+
+	c.compileStatements(block.Statements)
+}
+
+// Compiles stmt, returning whether it leaves a value on the stack.
+func (c *compiler) compileStatement(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		c.compileExpression(stmt.Expression)
+		return true
+	case *ast.LetStatement:
+		c.compileExpression(stmt.Value)
+		c.emit(OpSetName, c.node(stmt.Name))
+		return false
+	case *ast.ReturnStatement:
+		c.compileExpression(stmt.ReturnValue)
+		c.emit(OpReturnValue)
+		return false
+	default:
+		c.emit(OpEval, c.node(stmt))
+		return true
+	}
+}
+
+var infixOperators = map[string]Opcode{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+	">":  OpGreater,
+	"<":  OpLess,
+	">=": OpGreaterEqual,
+	"<=": OpLessEqual,
+	"in": OpIn,
+}
+
+func (c *compiler) compileExpression(expression ast.Expression) {
+	switch node := expression.(type) {
+	case nil:
+		c.emit(OpNone)
+	case *ast.IntegerLiteral:
+		// Big literals are left to the evaluator, which knows whether they're allowed
+		if node.Big != nil {
+			c.emit(OpEval, c.node(node))
+			return
+		}
+
+		c.emit(OpConstant, c.integer(node.Value))
+	case *ast.StringLiteral:
+		c.emit(OpConstant, c.string(node.Value))
+	case *ast.Boolean:
+		if node.Value { c.emit(OpTrue) } else { c.emit(OpFalse) }
+	case *ast.Identifier:
+		if node.TokenLiteral() == "nil" {
+			c.emit(OpNil)
+			return
+		}
+
+		c.emitAt(node.Token, OpGetName, c.node(node))
+	case *ast.PrefixExpression:
+		op, ok := map[string]Opcode{"-": OpMinus, "!": OpBang}[node.Operator]
+		if !ok {
+			c.emit(OpEval, c.node(node))
+			return
+		}
+
+		c.compileExpression(node.Right)
+		c.emitAt(node.Token, op)
+	case *ast.InfixExpression:
+		op, ok := infixOperators[node.Operator]
+		if !ok {
+			c.emit(OpEval, c.node(node))
+			return
+		}
+
+		c.compileExpression(node.Left)
+		c.compileExpression(node.Right)
+		c.emitAt(node.Token, op)
+	case *ast.IfExpression:
+		c.compileExpression(node.Condition)
+		jumpNotTruthy := c.emit(OpJumpNotTruthy, 0)
+
+		c.compileBlock(node.Consequence)
+		jump := c.emit(OpJump, 0)
+
+		c.patchJump(jumpNotTruthy)
+
+		if node.Alternative != nil {
+			c.compileBlock(node.Alternative)
+		} else {
+			c.emit(OpNil)
+		}
+
+		c.patchJump(jump)
+	case *ast.FunctionLiteral:
+		c.emit(OpFunction, c.node(node))
+	case *ast.CallExpression:
+		if len(node.Arguments) > 255 || hasSpread(node.Arguments) {
+			c.emit(OpEval, c.node(node))
+			return
+		}
+
+		c.compileExpression(node.Function)
+		for _, arg := range node.Arguments {
+			c.compileExpression(arg)
+		}
+
+		if node.IsTail {
+			c.emitAt(node.Token, OpTailCall, len(node.Arguments))
+		} else {
+			c.emitAt(node.Token, OpCall, len(node.Arguments))
+		}
+	case *ast.ArrayLiteral:
+		if hasSpread(node.Elements) {
+			c.emit(OpEval, c.node(node))
+			return
+		}
+
+		for _, element := range node.Elements {
+			c.compileExpression(element)
+		}
+
+		c.emit(OpArray, len(node.Elements))
+	case *ast.IndexExpresssion:
+		c.compileExpression(node.Left)
+		c.compileExpression(node.Index)
+		c.emitAt(node.Token, OpIndex)
+	case *ast.MemberExpression:
+		c.compileExpression(node.Left)
+		c.emitAt(node.Token, OpMember, c.name(node.Member.Value))
+	default:
+		c.emit(OpEval, c.node(node))
+	}
+}
+
+func hasSpread(expressions []ast.Expression) bool {
+	for _, expression := range expressions {
+		if _, ok := expression.(*ast.SpreadElement); ok { return true }
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package compiler
+
+import (
+	"dux/ast"
+	"dux/lexer"
+	"dux/parser"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct{
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 0, 0, 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpCall, []int{3}, []byte{byte(OpCall), 3}},
+	}
+
+	for _, tc := range tests {
+		instruction := Make(tc.op, tc.operands...)
+
+		if string(instruction) != string(tc.expected) {
+			t.Errorf("wrong instruction for %d. want=%v, got=%v", tc.op, tc.expected, instruction)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"1 + 2", "0000 OpConstant 0\n0005 OpConstant 1\n0010 OpAdd\n0011 OpReturnValue\n"},
+		{"let x = 0; -x", "0000 OpConstant 0\n0005 OpSetName 0\n0010 OpGetName 1\n0015 OpMinus\n0016 OpReturnValue\n"},
+		{"1; 2", "0000 OpConstant 0\n0005 OpPop\n0006 OpConstant 1\n0011 OpReturnValue\n"},
+		{"let x = 1;", "0000 OpConstant 0\n0005 OpSetName 0\n0010 OpNone\n0011 OpReturnValue\n"},
+		{
+			"if (true) { 10 }",
+			"0000 OpTrue\n0001 OpJumpNotTruthy 16\n0006 OpConstant 0\n0011 OpJump 17\n0016 OpNil\n0017 OpReturnValue\n",
+		},
+		{"f(1, nil)", "0000 OpGetName 0\n0005 OpConstant 0\n0010 OpNil\n0011 OpCall 2\n0013 OpReturnValue\n"},
+		{"[1, 1][0].len()", "0000 OpConstant 0\n0005 OpConstant 0\n0010 OpArray 2\n0015 OpConstant 1\n0020 OpIndex\n0021 OpMember 0\n0026 OpCall 0\n0028 OpReturnValue\n"},
+		{"fn(x) { x }", "0000 OpFunction 0\n0005 OpReturnValue\n"},
+		{"match (x) { _ => 1 }", "0000 OpEval 0\n0005 OpReturnValue\n"},
+		{"f(...xs)", "0000 OpEval 0\n0005 OpReturnValue\n"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).ParseProgram()
+		bytecode := Compile(program)
+
+		if bytecode.Instructions.String() != tc.expected {
+			t.Errorf("wrong instructions for %q.\nwant=\n%s\ngot=\n%s", tc.input, tc.expected, bytecode.Instructions)
+		}
+	}
+}
+
+func TestCompileFunction(t *testing.T) {
+	program := parser.New(lexer.New("fn(n) { return n; }")).ParseProgram()
+	literal := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	expected := "0000 OpGetName 0\n0005 OpReturnValue\n0006 OpNone\n0007 OpReturnValue\n"
+
+	if got := CompileFunction(literal.Body).Instructions.String(); got != expected {
+		t.Errorf("wrong instructions.\nwant=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestFunctionBytecodeCache(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn(n) { fn() { n } }; f(1)")).ParseProgram()
+	outer := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	inner := outer.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	bytecode := Compile(program)
+	code := bytecode.Function(outer.Body)
+
+	if bytecode.Function(outer.Body) != code {
+		t.Errorf("function body compiled twice by the same program")
+	}
+
+	// Functions called from a function share the program's bytecode
+	if code.Function(inner.Body) != bytecode.Function(inner.Body) {
+		t.Errorf("function bodies don't share the program's bytecode")
+	}
+
+	if Compile(program).Function(outer.Body) == code {
+		t.Errorf("function bytecode shared between compiled programs")
+	}
+}
@@ -0,0 +1,321 @@
+/*
+	Package dux embeds the dx interpreter in Go programs:
+
+		interp := dux.New()
+		interp.SetGlobal("limit", 10)
+		interp.Register("double", func(args ...any) (any, error) { return args[0].(int64) * 2, nil })
+
+		program, err := interp.Compile("double(limit)")
+		if err != nil { ... }
+
+		result, err := interp.Run(ctx, program) // int64(20)
+
+	Values cross between Go and dx as native Go values (see ToObject and
+	ToNative), and programs failing do so with Go errors of type *Error.
+*/
+package dux
+
+import (
+	"context"
+	"dux/ast"
+	"dux/evaluator"
+	"dux/lexer"
+	"dux/object"
+	"dux/optimizer"
+	"dux/parser"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+/*
+	An Interpreter runs dx programs in an environment of its own, which the
+	globals the programs bind stay in between runs. It runs one program at a
+	time.
+*/
+type Interpreter struct {
+	env *object.Environment
+
+	Limits evaluator.Limits // Applied to every run, none by default
+}
+
+// Returns an interpreter with the default builtins.
+func New() *Interpreter {
+	env := object.NewEnvironment()
+	env.SetBuiltins(evaluator.Builtins())
+
+	return &Interpreter{env: env}
+}
+
+/*
+	Returns the builtins of the interpreter, along with the methods of the
+	builtin types, which can be added to or removed from. Globals and names
+	bound by programs shadow the builtins.
+*/
+func (i *Interpreter) Builtins() *object.Registry { return i.env.Builtins() }
+
+/*
+	Replaces the builtins of the interpreter (i.e. with a restricted set, such
+	as evaluator.Builtins().Restrict("len", "push"), which can't spawn tasks
+	nor call the methods of strings either). Programs compiled before
+	may name builtins that are gone, which fail when they're reached.
+*/
+func (i *Interpreter) SetBuiltins(builtins *object.Registry) { i.env.SetBuiltins(builtins) }
+
+/*
+	Sets the streams builtins such as puts and print do I/O with, those of the
+	process by default.
+*/
+func (i *Interpreter) SetRuntime(runtime *object.Runtime) { i.env.SetRuntime(runtime) }
+
+/*
+	Makes integers overflowing 64 bits fail programs with an OverflowError,
+	instead of being promoted to big integers, which they are by default.
+*/
+func (i *Interpreter) SetStrictIntegers(strict bool) { i.env.SetStrictIntegers(strict) }
+
+// A Program is dx source compiled for the interpreter that compiled it.
+type Program struct {
+	program *ast.Program
+}
+
+/*
+	An Error is a dx program failing to compile or run. Kind is SyntaxError for
+	programs that don't parse, NameError for names defined nowhere, and the kind
+	of the dx error otherwise (see object.Error), as are Code and Values.
+*/
+type Error struct {
+	Kind    string
+	Code    string
+	Message string
+	Line    int // Position of the failing expression, 0 when unknown
+	Column  int
+	Values  []any          // Values the error is about, converted with ToNative
+	Value   any            // The thrown value of a UserError
+	Trace   []object.Frame // Calls the error unwound, innermost first (see object.Error.Traceback)
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 { return fmt.Sprintf("%s: %s", e.Kind, e.Message) }
+
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Kind, e.Message)
+}
+
+/*
+	Parses, optimizes and resolves src. Names used by src must be defined by
+	the program itself or bound in the interpreter already. Every error found
+	is returned, joined.
+*/
+func (i *Interpreter) Compile(src string) (*Program, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		errs := []error{}
+		for _, msg := range p.Errors() {
+			errs = append(errs, &Error{Kind: "SyntaxError", Code: "syntax_error", Message: msg})
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	program = optimizer.Optimize(program)
+
+	if resolveErrors := evaluator.Resolve(program, i.env); len(resolveErrors) != 0 {
+		errs := []error{}
+		for _, err := range resolveErrors {
+			errs = append(errs, &Error{Kind: "NameError", Code: "identifier_not_found", Message: err.Message, Line: err.Line, Column: err.Column})
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	return &Program{program: program}, nil
+}
+
+/*
+	Runs program until it's done or ctx is, returning its result as a native
+	value. A program failing, or hitting the interpreter's limits, returns an
+	*Error.
+*/
+func (i *Interpreter) Run(ctx context.Context, program *Program) (any, error) {
+	result := evaluator.EvalContext(ctx, program.program, i.env, i.Limits)
+
+	if err, ok := result.(*object.Error); ok { return nil, toError(err) }
+
+	return ToNative(result), nil
+}
+
+// Binds name to value, converted with ToObject, in the interpreter's globals.
+func (i *Interpreter) SetGlobal(name string, value any) error {
+	obj, err := ToObject(value)
+	if err != nil { return err }
+
+	i.env.Set(name, obj)
+
+	return nil
+}
+
+// Returns the global bound to name, converted with ToNative.
+func (i *Interpreter) GetGlobal(name string) (any, bool) {
+	obj, ok := i.env.Get(name)
+	if !ok { return nil, false }
+
+	return ToNative(obj), true
+}
+
+/*
+	Adds a builtin named name to the interpreter's builtins, calling fn with its
+	arguments converted with ToNative and converting its result back with
+	ToObject. An error returned by fn fails the call with a RuntimeError, which
+	dx code can catch.
+*/
+func (i *Interpreter) Register(name string, fn func(args ...any) (any, error)) {
+	i.Builtins().Set(name, &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		natives := make([]any, len(args))
+		for j, arg := range args {
+			natives[j] = ToNative(arg)
+		}
+
+		result, err := fn(natives...)
+		if err != nil { return &object.Error{Message: err.Error(), Kind: "RuntimeError", Code: "go_error"} }
+
+		obj, err := ToObject(result)
+		if err != nil { return &object.Error{Message: err.Error(), Kind: "TypeError", Code: "invalid_result"} }
+
+		return obj
+	}})
+}
+
+func toError(err *object.Error) *Error {
+	converted := &Error{Kind: err.Kind, Code: err.Code, Message: err.Message, Line: err.Line, Column: err.Column, Trace: err.Trace}
+	for _, value := range err.Values {
+		converted.Values = append(converted.Values, ToNative(value))
+	}
+	if err.Value != nil {
+		converted.Value = ToNative(err.Value)
+	}
+
+	return converted
+}
+
+/*
+	Converts a Go value to a dx object: integers to INTEGER, strings to STRING,
+	bools to BOOLEAN, nil to nil, []any to ARRAY and map[string]any to HASH,
+	converting their elements in turn, and *big.Int to INTEGER of any size.
+	Hashes get the keys of maps in sorted order. Objects are returned as they are, and
+	other values are bound through reflection: functions become builtins (see
+	Func) and structs objects with readable fields and callable methods.
+*/
+func ToObject(value any) (object.Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return evaluator.NIL, nil
+	case object.Object:
+		return value, nil
+	case int64:
+		return &object.Integer{Value: value}, nil
+	case int:
+		return &object.Integer{Value: int64(value)}, nil
+	case int32:
+		return &object.Integer{Value: int64(value)}, nil
+	case string:
+		return &object.String{Value: value}, nil
+	case bool:
+		if value { return evaluator.TRUE, nil }
+		return evaluator.FALSE, nil
+	case []any:
+		elements := make([]object.Object, len(value))
+		for i, element := range value {
+			obj, err := ToObject(element)
+			if err != nil { return nil, err }
+
+			elements[i] = obj
+		}
+		return &object.Array{Elements: elements}, nil
+	case map[string]any:
+		// Go maps have no order, so the keys are set in sorted order
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		hash := object.NewHash(len(value))
+		for _, key := range keys {
+			obj, err := ToObject(value[key])
+			if err != nil { return nil, err }
+
+			hash.Set(&object.String{Value: key}, obj)
+		}
+		return hash, nil
+	default:
+		return fromValue(reflect.ValueOf(value))
+	}
+}
+
+/*
+	Converts a dx object to a Go value, the other way around from ToObject.
+	Integers too large for an int64 become a *big.Int. Hashes become a
+	map[string]any when all their keys are strings, and a map[any]any of the
+	keys converted the same way otherwise, so that {1: "a", "1": "b"} keeps
+	both pairs; array keys become Go arrays ([n]any), which unlike slices
+	can be map keys. Go structs bound to dx are returned as the pointer to them they're
+	accessed through, and objects with no Go counterpart (i.e. dx functions)
+	as they are.
+*/
+func ToNative(obj object.Object) any {
+	switch obj := obj.(type) {
+	case *GoObject:
+		return obj.Value.Interface()
+	case nil, *object.Nil:
+		return nil
+	case *object.Integer:
+		return obj.Value
+	case *object.BigInteger:
+		return new(big.Int).Set(obj.Value)
+	case *object.String:
+		return obj.Value
+	case *object.Boolean:
+		return obj.Value
+	case *object.Array:
+		elements := make([]any, len(obj.Elements))
+		for i, element := range obj.Elements {
+			elements[i] = ToNative(element)
+		}
+		return elements
+	case *object.Hash:
+		pairs := make(map[string]any, obj.Len())
+		for _, pair := range obj.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok { return nativeHash(obj) }
+
+			pairs[key.Value] = ToNative(pair.Value)
+		}
+		return pairs
+	default:
+		return obj
+	}
+}
+
+func nativeHash(hash *object.Hash) map[any]any {
+	pairs := make(map[any]any, hash.Len())
+	for _, pair := range hash.Pairs() {
+		pairs[nativeKey(pair.Key)] = ToNative(pair.Value)
+	}
+
+	return pairs
+}
+
+// Converts a hash key like ToNative, but arrays to Go arrays, which are comparable.
+func nativeKey(key object.Object) any {
+	array, ok := key.(*object.Array)
+	if !ok { return ToNative(key) }
+
+	value := reflect.New(reflect.ArrayOf(len(array.Elements), anyType)).Elem()
+	for i, element := range array.Elements {
+		if native := nativeKey(element); native != nil { value.Index(i).Set(reflect.ValueOf(native)) }
+	}
+
+	return value.Interface()
+}
@@ -0,0 +1,241 @@
+package dux
+
+import (
+	"context"
+	"dux/object"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"1 + 2", int64(3)},
+		{`"a" + "b"`, "ab"},
+		{"1 < 2", true},
+		{"if (false) { 1 }", nil},
+		{"[1, \"a\", [true]]", []any{int64(1), "a", []any{true}}},
+		{`{"a": 1, "b": [2]}`, map[string]any{"a": int64(1), "b": []any{int64(2)}}},
+		{`{"a": 1, 2: "b"}`, map[any]any{"a": int64(1), int64(2): "b"}},
+		{`{1: "a", "1": "b", true: "c"}`, map[any]any{int64(1): "a", "1": "b", true: "c"}},
+		{`{[1, [true, "x"]]: "a", "x": "b"}`, map[any]any{[2]any{int64(1), [2]any{true, "x"}}: "a", "x": "b"}},
+		{"let x = 1; x + 1", int64(2)},
+	}
+
+	for _, tc := range tests {
+		result, err := run(New(), tc.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tc.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("wrong result for %q. want=%#v, got=%#v", tc.input, tc.expected, result)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let = 1", "SyntaxError: expected next token to be IDENT, got = instead"},
+		{"1 + missing", "1:5: NameError: identifier not found: missing"},
+		{"1 + true", "1:3: TypeError: type mismatch: INTEGER + BOOLEAN"},
+		{`throw "boom"`, "1:1: UserError: boom"},
+		{"fail(1)", "1:5: RuntimeError: failed with 1"},
+	}
+
+	for _, tc := range tests {
+		interp := New()
+		interp.Register("fail", func(args ...any) (any, error) { return nil, errors.New("failed with 1") })
+
+		_, err := run(interp, tc.input)
+		if err == nil {
+			t.Errorf("expected an error for %q", tc.input)
+			continue
+		}
+
+		var dxErr *Error
+		if !errors.As(err, &dxErr) || dxErr.Error() != tc.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tc.input, tc.expected, err)
+		}
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	interp := New()
+
+	if err := interp.SetGlobal("items", []any{1, "two", map[string]any{"three": int64(3)}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := interp.SetGlobal("ratio", 0.5); err == nil {
+		t.Errorf("expected floats not to convert")
+	}
+
+	interp.Register("sum", func(args ...any) (any, error) {
+		total := int64(0)
+		for _, arg := range args { total += arg.(int64) }
+		return total, nil
+	})
+
+	if _, err := run(interp, `let total = sum(items[0], len(items), items[2]["three"])`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Globals bound by a run stay for the next ones
+	result, err := run(interp, "total * 2")
+	if err != nil || result != int64(14) {
+		t.Errorf("wrong result. want=14, got=%v (%v)", result, err)
+	}
+
+	if total, ok := interp.GetGlobal("total"); !ok || total != int64(7) {
+		t.Errorf("wrong global total. want=7, got=%v", total)
+	}
+
+	if _, ok := interp.GetGlobal("missing"); ok {
+		t.Errorf("expected no global missing")
+	}
+
+	// Maps have no order of their own, so their keys are sorted
+	if err := interp.SetGlobal("config", map[string]any{"b": 1, "c": 2, "a": 3}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err = run(interp, `to_array(config)`)
+	if err != nil || !reflect.DeepEqual(result, []any{"a", "b", "c"}) {
+		t.Errorf("wrong keys. want=[a b c], got=%v (%v)", result, err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	interp := New()
+	interp.Limits.MaxSteps = 1000
+
+	_, err := run(interp, "let f = fn(n) { f(n + 1) }; f(0)")
+
+	var dxErr *Error
+	if !errors.As(err, &dxErr) || dxErr.Kind != "LimitError" {
+		t.Errorf("expected a LimitError, got %v", err)
+	}
+}
+
+func run(interp *Interpreter, input string) (any, error) {
+	program, err := interp.Compile(input)
+	if err != nil { return nil, err }
+
+	return interp.Run(context.Background(), program)
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"len([1, 2])", int64(2)},
+		{"let len = fn(x) { 42 }; len([1])", int64(42)},
+		{"double(2)", int64(4)},
+		{"let double = fn(x) { x }; double(2)", int64(2)},
+		{"chan()", "identifier not found: chan"},
+		{"[1].push(2)", "unknown member push on ARRAY"},
+		{`"abc".upper()`, "unknown member upper on STRING"},
+		{"spawn len([1])", "spawning tasks is disabled"},
+		{"7.triple()", int64(21)},
+	}
+
+	for _, tc := range tests {
+		interp := New()
+		interp.SetBuiltins(interp.Builtins().Restrict("len", "double"))
+		interp.Register("double", func(args ...any) (any, error) { return args[0].(int64) * 2, nil })
+		interp.Builtins().SetMethod(object.INTEGER_OBJ, "triple", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return &object.Integer{Value: args[0].(*object.Integer).Value * 3}
+		}})
+
+		result, err := run(interp, tc.input)
+		if err != nil {
+			var dxErr *Error
+			if errors.As(err, &dxErr) { result = dxErr.Message }
+		}
+
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("wrong result for %q. want=%#v, got=%#v", tc.input, tc.expected, result)
+		}
+	}
+
+	// Builtins belong to each interpreter
+	if _, err := run(New(), "double(2)"); err == nil {
+		t.Errorf("expected double not to be a builtin of other interpreters")
+	}
+
+	if _, err := run(New(), "7.triple()"); err == nil {
+		t.Errorf("expected triple not to be a method of other interpreters")
+	}
+
+	if names := New().Builtins().Names(); len(names) == 0 || names[0] != "chan" {
+		t.Errorf("wrong default builtins. got=%v", names)
+	}
+}
+
+func TestRuntime(t *testing.T) {
+	var stdout, stderr strings.Builder
+	interp := New()
+	interp.SetRuntime(&object.Runtime{Stdout: &stdout, Stderr: &stderr})
+
+	if _, err := run(interp, `puts("out"); eprint("err")`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if stdout.String() != "\"out\"\n" || stderr.String() != "\"err\"" {
+		t.Errorf("wrong output. got=%q/%q", stdout.String(), stderr.String())
+	}
+}
+
+func TestTrace(t *testing.T) {
+	_, err := run(New(), "let f = fn(x) { x + true }; let g = fn() { f(1) + 1 }; g()")
+
+	var dxErr *Error
+	if !errors.As(err, &dxErr) {
+		t.Fatalf("expected an error, got %v", err)
+	}
+
+	expected := []object.Frame{{Function: "f", Line: 1, Column: 45}, {Function: "g", Line: 1, Column: 57}}
+	if !reflect.DeepEqual(dxErr.Trace, expected) {
+		t.Errorf("wrong trace. want=%v, got=%v", expected, dxErr.Trace)
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	interp := New()
+
+	huge, _ := new(big.Int).SetString("18446744073709551616", 10)
+	if err := interp.SetGlobal("huge", huge); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := run(interp, "huge * 2")
+	if expected, _ := new(big.Int).SetString("36893488147419103232", 10); err != nil || expected.Cmp(result.(*big.Int)) != 0 {
+		t.Errorf("wrong result. want=%s, got=%v (%v)", expected, result, err)
+	}
+
+	// Big integers coming back in range are int64 again
+	result, err = run(interp, "huge / 4294967296")
+	if err != nil || result != int64(4294967296) {
+		t.Errorf("wrong result. want=4294967296, got=%v (%v)", result, err)
+	}
+
+	interp.SetStrictIntegers(true)
+
+	_, err = run(interp, "9223372036854775807 + 1")
+
+	var dxErr *Error
+	if !errors.As(err, &dxErr) || dxErr.Kind != "OverflowError" || dxErr.Code != "integer_overflow" {
+		t.Errorf("expected an OverflowError, got %v", err)
+	}
+}
@@ -3,26 +3,63 @@ package evaluator
 import (
 	"dux/object"
 	"fmt"
+	"io"
+	"sort"
 )
 
+/*
+	Result values are tagged values of this builtin enum, so they can be
+	matched on like any other enum (i.e. match (r) { Ok(v) => v, Err(e) => 0 }).
+*/
+var resultEnum = func() *object.Enum {
+	enum := &object.Enum{Name: "Result"}
+	enum.Variants = []*object.EnumVariant{
+		{Enum: enum, Name: "Ok", Fields: []string{"value"}},
+		{Enum: enum, Name: "Err", Fields: []string{"error"}},
+	}
+
+	return enum
+}()
+
+var (
+	okVariant  = resultEnum.Variants[0]
+	errVariant = resultEnum.Variants[1]
+)
+
+func asResult(obj object.Object) (*object.EnumValue, bool) {
+	value, ok := obj.(*object.EnumValue)
+	if !ok || value.Variant.Enum != resultEnum { return nil, false }
+
+	return value, true
+}
+
+/*
+	Returns a registry of the default builtins and methods, for a root
+	environment to be given (see object.Environment.SetBuiltins). Environments
+	given none use the defaults directly.
+*/
+func Builtins() *object.Registry { return object.NewRegistry(builtins, methods) }
+
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 { return newError("wrong number of arguments. got=%d, want=%d", len(args), 1) }
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
 			
 			switch arg := args[0].(type) {
 			case *object.String:
 				return &object.Integer{Value: int64(len(arg.Value))}
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Range:
+				return &object.Integer{Value: arg.Len()}
 			default:
-				return newError("argument to `len` not supported, got %s", args[0].Type())
+				return newKindError("TypeError", "invalid_argument", nil, "argument to `len` not supported, got %s", args[0].Type())
 			}
 		},
 	},
 	"first": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 { return newError("wrong number of arguments. got=%d, want=%d", len(args), 1) }
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
 
 			switch arg := args[0].(type) {
 			case *object.Array:
@@ -30,7 +67,7 @@ var builtins = map[string]*object.Builtin{
 			case *object.String:
 				if len(arg.Value) > 0 { return &object.String{Value: string(arg.Value[0])} }
 			default:
-				return newError("invalid argument %s to 'first', must be ARRAY or STRING.", arg.Type())
+				return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'first', must be ARRAY or STRING.", arg.Type())
 			}
 
 			return NIL
@@ -38,7 +75,7 @@ var builtins = map[string]*object.Builtin{
 	},
 	"last": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 { return newError("wrong number of arguments. got=%d, want=%d", len(args), 1) }
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
 
 			switch arg := args[0].(type) {
 			case *object.Array:
@@ -48,15 +85,16 @@ var builtins = map[string]*object.Builtin{
 				length := len(arg.Value)
 				if length > 0 { return &object.String{Value: string(arg.Value[length - 1])} }
 			default:
-				return newError("invalid argument %s to 'last', must be ARRAY or STRING", arg.Type())
+				return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'last', must be ARRAY or STRING", arg.Type())
 			}
 
 			return NIL
 		},
 	},
 	"tail": {
+		Allocates: copyAllocation,
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 { return newError("wrong number of arguments. got=%d, want=%d", len(args), 1) }
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
 
 			switch arg := args[0].(type) {
 			case *object.Array:
@@ -70,15 +108,16 @@ var builtins = map[string]*object.Builtin{
 					return &object.Array{Elements: newElements}
 				}
 			default:
-				return newError("invalid argument %s to 'tail', must be ARRAY", arg.Type())
+				return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'tail', must be ARRAY", arg.Type())
 			}
 
 			return NIL
 		},
 	},
 	"head": {
+		Allocates: copyAllocation,
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 { return newError("wrong number of arguments. got=%d, want=%d", len(args), 1) }
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
 
 			switch arg := args[0].(type) {
 			case *object.Array:
@@ -90,14 +129,15 @@ var builtins = map[string]*object.Builtin{
 					return &object.Array{Elements: newElements}
 				}
 			default:
-				return newError("invalid argument %s to 'tail', must be ARRAY", arg.Type())
+				return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'tail', must be ARRAY", arg.Type())
 			}
 			return NIL
 		},
 	},
 	"push": {
+		Allocates: copyAllocation,
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 { return newError("wrong number of arguments. got=%d, want=%d", len(args), 2) }
+			if len(args) != 2 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 2) }
 
 			switch arg := args[0].(type) {
 			case *object.Array:
@@ -110,16 +150,237 @@ var builtins = map[string]*object.Builtin{
 
 				return &object.Array{Elements: newElements}
 			default:
-				return newError("invalid first argument %s to 'head', must be ARRAY", arg.Type())
+				return newKindError("TypeError", "invalid_argument", nil, "invalid first argument %s to 'head', must be ARRAY", arg.Type())
 			}
 		},
 	},
-	"puts": {
+	"to_array": {
+		Allocates: collectAllocation,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			iterable, ok := args[0].(object.Iterable)
+			if !ok {
+				return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'to_array', must be ARRAY, STRING, HASH, RANGE or GENERATOR", args[0].Type())
+			}
+
+			elements := iterate(iterable)
+			if len(elements) == 1 && isAbrupt(elements[0]) { return elements[0] }
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"sort": {
+		Allocates: copyAllocation,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			array, ok := args[0].(*object.Array)
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'sort', must be ARRAY", args[0].Type()) }
+
+			sorted := make([]object.Object, len(array.Elements))
+			copy(sorted, array.Elements)
+
+			// Elements are ordered with object.Compare, the first pair it can't order failing the sort
+			var failed object.Object
+			sort.SliceStable(sorted, func(i, j int) bool {
+				order, ok := object.Compare(sorted[i], sorted[j])
+				if !ok && failed == nil {
+					failed = newKindError("TypeError", "not_comparable", []object.Object{sorted[i], sorted[j]}, "cannot compare %s with %s", sorted[i].Type(), sorted[j].Type())
+				}
+
+				return order < 0
+			})
+			if failed != nil { return failed }
+
+			return &object.Array{Elements: sorted}
+		},
+	},
+	"contains": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 2) }
+
+			// Same as value in collection, comparing with object.Equal
+			return evalInExpression(args[1], args[0])
+		},
+	},
+	"chan": {
+		Allocates: bufferAllocation,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			// Unbuffered unless given a size
+			size := int64(0)
+			if len(args) == 1 {
+				if _, ok := args[0].(*object.BigInteger); ok {
+					return newKindError("OverflowError", "integer_overflow", args, "channel size out of range: %s", args[0].Inspect())
+				}
+
+				integer, ok := args[0].(*object.Integer)
+				if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `chan` must be INTEGER, got %s", args[0].Type()) }
+				if integer.Value < 0 { return newKindError("RuntimeError", "invalid_channel_size", nil, "channel size cannot be negative, got %d", integer.Value) }
+
+				size = integer.Value
+			}
+
+			return &object.Channel{Ch: make(chan object.Object, size)}
+		},
+	},
+	"send": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 2) }
+
+			channel, ok := args[0].(*object.Channel)
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `send` must be CHANNEL, got %s", args[0].Type()) }
+
+			if !channel.Send(args[1]) { return newKindError("RuntimeError", "closed_channel", nil, "send on closed channel") }
+
+			return NIL
+		},
+	},
+	"recv": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			channel, ok := args[0].(*object.Channel)
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `recv` must be CHANNEL, got %s", args[0].Type()) }
+
+			// A closed and drained channel keeps returning nil
+			value, ok := channel.Next()
+			if !ok { return NIL }
+
+			return value
+		},
+	},
+	"close": {
 		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			channel, ok := args[0].(*object.Channel)
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `close` must be CHANNEL, got %s", args[0].Type()) }
+
+			if !channel.Close() { return newKindError("RuntimeError", "closed_channel", nil, "close of closed channel") }
+
+			return NIL
+		},
+	},
+	"wait": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			task, ok := args[0].(*object.Task)
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `wait` must be TASK, got %s", args[0].Type()) }
+
+			return task.Wait()
+		},
+	},
+	"next": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			generator, ok := args[0].(*object.Generator)
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `next` must be GENERATOR, got %s", args[0].Type()) }
+
+			// An exhausted generator keeps returning nil
+			value, ok := generator.Next()
+			if !ok { return NIL }
+
+			return value
+		},
+	},
+	"type": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			return &object.String{Value: string(args[0].Type())}
+		},
+	},
+	"ok": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			return &object.EnumValue{Variant: okVariant, Payload: args}
+		},
+	},
+	"err": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			return &object.EnumValue{Variant: errVariant, Payload: args}
+		},
+	},
+	"is_ok": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			result, ok := asResult(args[0])
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'is_ok', must be Result", args[0].Type()) }
+
+			return nativeBoolToBooleanObject(result.Variant == okVariant)
+		},
+	},
+	"is_err": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			result, ok := asResult(args[0])
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'is_err', must be Result", args[0].Type()) }
+
+			return nativeBoolToBooleanObject(result.Variant == errVariant)
+		},
+	},
+	"unwrap": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 1) }
+
+			result, ok := asResult(args[0])
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'unwrap', must be Result", args[0].Type()) }
+
+			if result.Variant == errVariant {
+				return newKindError("RuntimeError", "unwrap_err", nil, "unwrap called on %s", result.Inspect())
+			}
+
+			return result.Payload[0]
+		},
+	},
+	"unwrap_or": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), 2) }
+
+			result, ok := asResult(args[0])
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "invalid argument %s to 'unwrap_or', must be Result", args[0].Type()) }
+
+			if result.Variant == errVariant { return args[1] }
+
+			return result.Payload[0]
+		},
+	},
+	"puts": {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				fmt.Fprintln(runtime.Out(), arg.Inspect())
 			}
 			return NIL
 		},
 	},
+	"print": {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			return write(runtime.Out(), args)
+		},
+	},
+	"eprint": {
+		RuntimeFn: func(runtime *object.Runtime, args ...object.Object) object.Object {
+			return write(runtime.Err(), args)
+		},
+	},
+}
+
+// Writes args to out separated by spaces, without a trailing newline.
+func write(out io.Writer, args []object.Object) object.Object {
+	for i, arg := range args {
+		if i > 0 { io.WriteString(out, " ") }
+		io.WriteString(out, arg.Inspect())
+	}
+
+	return NIL
 }
@@ -0,0 +1,92 @@
+package evaluator
+
+import (
+	"dux/ast"
+	"dux/object"
+	"dux/resolver"
+	"dux/token"
+)
+
+/*
+	The operations below expose the evaluator's semantics to other execution
+	engines (see package vm), so that running a program gives the same result,
+	down to error messages and positions, whatever engine runs it.
+*/
+
+func PrefixOperation(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+func InfixOperation(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+func CheckInteger(obj object.Object, env *object.Environment) object.Object {
+	return checkInteger(obj, env)
+}
+
+func Index(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+func Member(left object.Object, member string, env *object.Environment) object.Object {
+	return evalMemberExpression(left, member, env)
+}
+
+func Identifier(node *ast.Identifier, env *object.Environment) object.Object {
+	return evalIdentifier(node, env)
+}
+
+func Bind(env *object.Environment, ident *ast.Identifier, value object.Object) {
+	bind(env, ident, value)
+}
+
+/*
+	Resolves the identifiers of program to run in env (see package resolver),
+	returning an error for every name bound neither by program nor by env, nor
+	naming one of its builtins.
+*/
+func Resolve(program *ast.Program, env *object.Environment) []*resolver.Error {
+	return resolver.Resolve(program, func(name string) bool {
+		if _, ok := env.Get(name); ok { return true }
+
+		_, ok := lookupBuiltin(name, env)
+		return ok
+	})
+}
+
+/*
+	Charges what the operator, the call or the array literal about to be
+	evaluated allocates against the sandbox of env, if any, returning the
+	error of a sandbox over its budget.
+*/
+func AllocateInfix(operator string, left, right object.Object, env *object.Environment) object.Object {
+	return allocate(env, infixAllocation(operator, left, right))
+}
+
+func AllocateCall(fn object.Object, args []object.Object, env *object.Environment) object.Object {
+	return allocate(env, callAllocation(fn, args))
+}
+
+func AllocateArray(length int, env *object.Environment) object.Object {
+	return allocate(env, multiply(length, elementSize))
+}
+
+func ApplyFunction(fn object.Object, args []object.Object, env *object.Environment) object.Object {
+	return applyFunction(fn, args, env)
+}
+
+func Truthy(obj object.Object) bool { return truthy(obj) }
+
+// Records in the trace of obj, if it's an error, that it unwound a call of fn made at tok.
+func Traced(obj object.Object, fn *object.Function, tok token.Token) object.Object {
+	return atCallSite(traced(obj, fn), tok)
+}
+
+func AtCallSite(obj object.Object, tok token.Token) object.Object {
+	return atCallSite(obj, tok)
+}
+
+func WithPosition(obj object.Object, tok token.Token) object.Object {
+	return withPosition(obj, tok)
+}
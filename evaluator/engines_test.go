@@ -0,0 +1,27 @@
+package evaluator_test
+
+import (
+	"dux/ast"
+	"dux/compiler"
+	"dux/evaluator"
+	"dux/object"
+	"dux/optimizer"
+	"dux/vm"
+)
+
+/*
+	Runs every program of the evaluator tests on the bytecode vm as well, and
+	optimized before evaluating it.
+*/
+func init() {
+	evaluator.Engines["vm"] = func(program *ast.Program, env *object.Environment) object.Object {
+		return vm.Run(compiler.Compile(program), env)
+	}
+
+	evaluator.Engines["optimizer"] = func(program *ast.Program, env *object.Environment) object.Object {
+		program = optimizer.Optimize(program)
+		evaluator.Resolve(program, env)
+
+		return evaluator.Eval(program, env)
+	}
+}
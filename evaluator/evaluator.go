@@ -3,7 +3,11 @@ package evaluator
 import (
 	"dux/ast"
 	"dux/object"
+	"dux/token"
 	"fmt"
+	"math"
+	"math/big"
+	"reflect"
 	"strings"
 )
 
@@ -15,12 +19,17 @@ var (
 	ZERO   = &object.Integer{Value: 0}
 )
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if sandbox := env.Sandbox(); sandbox != nil {
+		if err := sandbox.Step(); err != nil { return err }
+	}
+
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
+		if node.Big != nil { return withPosition(checkInteger(&object.BigInteger{Value: node.Big}, env), node.Token) }
 		if node.Value == 0 { return ZERO }
 		return &object.Integer{Value: node.Value}
 	case *ast.Boolean:
@@ -28,16 +37,20 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 
-		if isError(right) { return right }
-		return evalPrefixExpression(node.Operator, right)
+		if isAbrupt(right) { return right }
+		return withPosition(checkInteger(evalPrefixExpression(node.Operator, right), env), node.Token)
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
-		if isError(left) { return left }
+		if isAbrupt(left) { return left }
 
 		right := Eval(node.Right, env)
-		if isError(right) { return right }
+		if isAbrupt(right) { return right }
+
+		if err := allocate(env, infixAllocation(node.Operator, left, right)); err != nil {
+			return withPosition(err, node.Token)
+		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return withPosition(checkInteger(evalInfixExpression(node.Operator, left, right), env), node.Token)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
@@ -45,45 +58,119 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 
-		if isError(val) { return val }
+		if isAbrupt(val) { return val }
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) { return val }
-		env.Set(node.Name.Value, val)
+		if isAbrupt(val) { return val }
+		bind(env, node.Name, val)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return withPosition(evalIdentifier(node, env), node.Token)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{Parameters: params, Body: body, Env: env, IsGenerator: node.IsGenerator, Name: node.Name}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
-		if isError(function) { return function }
+		if isAbrupt(function) { return function }
 
 		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		if len(args) == 1 && isAbrupt(args[0]) {
+			return withPosition(args[0], node.Token)
+		}
+
+		if node.IsTail {
+			switch function.(type) {
+			case *object.Function, *object.BoundMethod:
+				return &object.TailCall{Function: function, Arguments: args, Token: node.Token}
+			}
+		}
+
+		if err := allocate(env, callAllocation(function, args)); err != nil {
+			return withPosition(err, node.Token)
 		}
 
-		return applyFunction(function, args)
+		return atCallSite(withPosition(applyFunction(function, args, env), node.Token), node.Token)
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) { return elements[0] }
+		if len(elements) == 1 && isAbrupt(elements[0]) { return withPosition(elements[0], node.Token) }
+
+		if err := allocate(env, multiply(len(elements), elementSize)); err != nil {
+			return withPosition(err, node.Token)
+		}
 
 		return &object.Array{Elements: elements}
 	case *ast.IndexExpresssion:
 		left := Eval(node.Left, env)
-		if isError(left) { return left }
+		if isAbrupt(left) { return left }
 
 		index := Eval(node.Index, env)
-		if isError(index) { return index }
+		if isAbrupt(index) { return index }
 
-		return evalIndexExpression(left, index)
+		return withPosition(evalIndexExpression(left, index), node.Token)
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return withPosition(evalHashLiteral(node, env), node.Token)
+	case *ast.RangeExpression:
+		return withPosition(evalRangeExpression(node, env), node.Token)
+	case *ast.ForExpression:
+		return withPosition(evalForExpression(node, env), node.Token)
+	case *ast.StructStatement:
+		if err := checkTypeName(node.Name); err != nil { return withPosition(err, node.Token) }
+
+		bind(env, node.Name, evalStructStatement(node, env))
+	case *ast.EnumStatement:
+		if err := checkTypeName(node.Name); err != nil { return withPosition(err, node.Token) }
+
+		bind(env, node.Name, evalEnumStatement(node))
+	case *ast.MatchExpression:
+		return withPosition(evalMatchExpression(node, env), node.Token)
+	case *ast.MemberExpression:
+		left := Eval(node.Left, env)
+		if isAbrupt(left) { return left }
+
+		return withPosition(evalMemberExpression(left, node.Member.Value, env), node.Token)
+	case *ast.PropagateExpression:
+		value := Eval(node.Value, env)
+		if isAbrupt(value) { return value }
+
+		return withPosition(evalPropagateExpression(value), node.Token)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isAbrupt(val) { return val }
+
+		return withPosition(throwValue(val), node.Token)
+	case *ast.SpawnExpression:
+		if registry := env.Builtins(); registry != nil && !registry.CanSpawn() {
+			return withPosition(newKindError("RuntimeError", "spawn_disabled", nil, "spawning tasks is disabled"), node.Token)
+		}
+
+		function := Eval(node.Call.Function, env)
+		if isAbrupt(function) { return function }
+
+		args := evalExpressions(node.Call.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) {
+			return withPosition(args[0], node.Call.Token)
+		}
+
+		return object.NewTask(func() object.Object {
+			return atCallSite(withPosition(applyFunction(function, args, env), node.Call.Token), node.Call.Token)
+		})
+	case *ast.SelectExpression:
+		return withPosition(evalSelectExpression(node, env), node.Token)
+	case *ast.YieldStatement:
+		if !env.InGenerator() {
+			return withPosition(newKindError("RuntimeError", "yield_outside_generator", nil, "yield outside of a generator"), node.Token)
+		}
+
+		val := Eval(node.Value, env)
+		if isAbrupt(val) { return val }
+
+		// The generator was dropped: unwind its body like a return would
+		if !env.Yield(val) { return &object.ReturnValue{Value: NIL} }
 	}
 
 	return nil
@@ -127,31 +214,574 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newKindError("TypeError", "unknown_operator", []object.Object{right}, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[uint64]object.HashPair)
+	hash := object.NewHash(len(node.Pairs))
+
+	for _, pair := range node.Pairs {
+		if spread, ok := pair.Key.(*ast.SpreadElement); ok {
+			if err := evalHashSpread(hash, spread, env); err != nil { return err }
+			continue
+		}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
-		if isError(key) { return key }
+		key := Eval(pair.Key, env)
+		if isAbrupt(key) { return key }
 
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+		if _, ok := object.HashKeyOf(key); !ok {
+			return newKindError("KeyError", "unhashable_key", []object.Object{key}, "unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(pair.Value, env)
+		if isAbrupt(value) { return value }
+
+		hash.Set(key, value)
+	}
+
+	if err := allocate(env, multiply(hash.Len(), pairSize)); err != nil { return err }
+
+	return hash
+}
+
+// Sets the pairs of the hash spread evaluates to in hash, in their order.
+func evalHashSpread(hash *object.Hash, spread *ast.SpreadElement, env *object.Environment) object.Object {
+	value := Eval(spread.Value, env)
+	if isAbrupt(value) { return value }
+
+	spreadHash, ok := value.(*object.Hash)
+	if !ok {
+		return newKindError("TypeError", "not_spreadable", []object.Object{value}, "spread operator not supported in hash literal: %s", value.Type())
+	}
+
+	for _, pair := range spreadHash.Pairs() {
+		hash.Set(pair.Key, pair.Value)
+	}
+
+	return nil
+}
+
+// Rejects struct and enum names that would pass for a built-in type (see object.IsBuiltinType).
+func checkTypeName(name *ast.Identifier) object.Object {
+	if !object.IsBuiltinType(name.Value) { return nil }
+
+	return newKindError("NameError", "reserved_type_name", []object.Object{&object.String{Value: name.Value}}, "%s is the name of a built-in type", name.Value)
+}
+
+func evalStructStatement(node *ast.StructStatement, env *object.Environment) *object.Struct {
+	structObj := &object.Struct{Name: node.Name.Value, Methods: map[string]*object.Function{}}
+
+	for _, field := range node.Fields {
+		structObj.Fields = append(structObj.Fields, field.Value)
+	}
+
+	for _, method := range node.Methods {
+		structObj.Methods[method.Name.Value] = &object.Function{
+			Parameters: method.Function.Parameters,
+			Body: method.Function.Body,
+			Env: env,
+			IsGenerator: method.Function.IsGenerator,
+			Name: method.Function.Name,
+		}
+	}
+
+	if inspect, ok := structObj.Methods["inspect"]; ok {
+		structObj.Inspector = func(instance *object.Instance) string {
+			rendered := applyFunction(inspect, []object.Object{instance}, env)
+			if str, ok := rendered.(*object.String); ok {
+				return str.Value
+			}
+
+			return rendered.Inspect()
+		}
+	}
+
+	return structObj
+}
+
+func evalMemberExpression(left object.Object, member string, env *object.Environment) object.Object {
+	switch left := left.(type) {
+	case *object.Instance:
+		if value, ok := left.Fields[member]; ok {
+			return value
+		}
+
+		if method, ok := left.Struct.Methods[member]; ok {
+			return &object.BoundMethod{Receiver: left, Method: method}
+		}
+
+		if method, ok := lookupMethod(left, member, env); ok {
+			return method
+		}
+
+		return newKindError("KeyError", "unknown_member", []object.Object{left}, "unknown member %s on %s", member, left.Type())
+	case *object.Enum:
+		variant, ok := left.Variant(member)
+		if !ok { return newKindError("KeyError", "unknown_member", []object.Object{left}, "unknown variant %s on %s", member, left.Name) }
+
+		if len(variant.Fields) == 0 {
+			return &object.EnumValue{Variant: variant}
+		}
+
+		return variant
+	case *object.Exception:
+		return evalExceptionMember(left, member, env)
+	case *object.EnumValue:
+		for i, field := range left.Variant.Fields {
+			if field == member { return left.Payload[i] }
+		}
+
+		if method, ok := lookupMethod(left, member, env); ok {
+			return method
+		}
+
+		return newKindError("KeyError", "unknown_member", []object.Object{left}, "unknown member %s on %s", member, left.Variant.Inspect())
+	case object.Accessible:
+		if value, ok := left.Member(member); ok {
+			return value
+		}
+
+		if method, ok := lookupMethod(left, member, env); ok {
+			return method
+		}
+
+		return newKindError("KeyError", "unknown_member", []object.Object{left}, "unknown member %s on %s", member, left.Type())
+	default:
+		if method, ok := lookupMethod(left, member, env); ok {
+			return method
+		}
+
+		return newKindError("KeyError", "unknown_member", []object.Object{left}, "unknown member %s on %s", member, left.Type())
+	}
+}
+
+func evalExceptionMember(exception *object.Exception, member string, env *object.Environment) object.Object {
+	err := exception.Error
+
+	switch member {
+	case "message":
+		return &object.String{Value: err.Message}
+	case "kind":
+		return &object.String{Value: err.Kind}
+	case "code":
+		return &object.String{Value: err.Code}
+	case "values":
+		return &object.Array{Elements: append([]object.Object{}, err.Values...)}
+	case "value":
+		if err.Value == nil { return NIL }
+		return err.Value
+	case "line":
+		return &object.Integer{Value: int64(err.Line)}
+	case "column":
+		return &object.Integer{Value: int64(err.Column)}
+	case "position":
+		return &object.String{Value: err.Position()}
+	default:
+		if method, ok := lookupMethod(exception, member, env); ok {
+			return method
+		}
+
+		return newKindError("KeyError", "unknown_member", []object.Object{exception}, "unknown member %s on %s", member, exception.Type())
+	}
+}
+
+func evalEnumStatement(node *ast.EnumStatement) *object.Enum {
+	enum := &object.Enum{Name: node.Name.Value}
+
+	for _, v := range node.Variants {
+		variant := &object.EnumVariant{Enum: enum, Name: v.Name.Value}
+
+		for _, field := range v.Fields {
+			variant.Fields = append(variant.Fields, field.Value)
+		}
+
+		enum.Variants = append(enum.Variants, variant)
+	}
+
+	return enum
+}
+
+func newEnumValue(variant *object.EnumVariant, args []object.Object) object.Object {
+	if len(args) != len(variant.Fields) {
+		return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), len(variant.Fields))
+	}
+
+	return &object.EnumValue{Variant: variant, Payload: args}
+}
+
+/*
+	Evaluates the body of the first arm whose pattern matches the subject. When
+	the subject is a tagged value, the arms must cover every variant of its enum
+	(or have a '_' arm), otherwise the match is rejected before any arm runs.
+*/
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if isAbrupt(subject) { return subject }
+
+	if value, ok := subject.(*object.EnumValue); ok {
+		// Patterns are resolved in the scope of their arm
+		if err := checkExhaustive(node, value.Variant.Enum, object.NewScope(env, 0)); err != nil {
+			return err
+		}
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewScope(env, 0)
+
+		matched := matchPattern(arm.Pattern, subject, armEnv)
+		if isAbrupt(matched) { return matched }
+
+		if matched == TRUE {
+			return Eval(arm.Body, armEnv)
+		}
+	}
+
+	return newKindError("RuntimeError", "no_match", nil, "no match arm for %s", subject.Inspect())
+}
+
+func checkExhaustive(node *ast.MatchExpression, enum *object.Enum, env *object.Environment) object.Object {
+	covered := map[string]bool{}
+
+	for _, arm := range node.Arms {
+		if isWildcard(arm.Pattern) { return nil }
+
+		if variant := patternVariant(arm.Pattern, enum, env); variant != nil {
+			covered[variant.Name] = true
 		}
+	}
+
+	missing := []string{}
+	for _, variant := range enum.Variants {
+		if !covered[variant.Name] {
+			missing = append(missing, variant.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return newKindError("RuntimeError", "non_exhaustive_match", nil, "non-exhaustive match on %s: missing %s", enum.Name, strings.Join(missing, ", "))
+	}
 
-		value := Eval(valueNode, env)
-		if isError(value) { return value }
+	return nil
+}
 
-		hashed := hashKey.HashKey()
+func isWildcard(pattern ast.Expression) bool {
+	ident, ok := pattern.(*ast.Identifier)
+	return ok && ident.Value == "_"
+}
 
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+/*
+	Returns the variant of enum named by a pattern, if any: Name, Enum.Name,
+	Name(bindings...) or Enum.Name(bindings...).
+*/
+func patternVariant(pattern ast.Expression, enum *object.Enum, env *object.Environment) *object.EnumVariant {
+	if call, ok := pattern.(*ast.CallExpression); ok {
+		pattern = call.Function
 	}
 
-	return &object.Hash{Pairs: pairs}
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if variant, ok := enum.Variant(pattern.Value); ok {
+			return variant
+		}
+	case *ast.MemberExpression:
+		left, ok := pattern.Left.(*ast.Identifier)
+		if !ok { return nil }
+
+		if evalIdentifier(left, env) != enum { return nil }
+
+		if variant, ok := enum.Variant(pattern.Member.Value); ok {
+			return variant
+		}
+	}
+
+	return nil
+}
+
+/*
+	Returns TRUE when pattern matches subject, binding any payload names into
+	env, FALSE when it doesn't, or an error for malformed patterns.
+*/
+func matchPattern(pattern ast.Expression, subject object.Object, env *object.Environment) object.Object {
+	if isWildcard(pattern) { return TRUE }
+
+	if value, ok := subject.(*object.EnumValue); ok {
+		if variant := patternVariant(pattern, value.Variant.Enum, env); variant != nil {
+			if variant != value.Variant { return FALSE }
+
+			call, ok := pattern.(*ast.CallExpression)
+			if !ok { return TRUE }
+
+			if len(call.Arguments) != len(variant.Fields) {
+				return newKindError("ArityError", "wrong_arguments", nil, "wrong number of bindings for %s. got=%d, want=%d", variant.Inspect(), len(call.Arguments), len(variant.Fields))
+			}
+
+			for i, arg := range call.Arguments {
+				binding, ok := arg.(*ast.Identifier)
+				if !ok { return newKindError("RuntimeError", "invalid_pattern", nil, "invalid binding in pattern: %s", arg.String()) }
+
+				if binding.Value != "_" {
+					bind(env, binding, value.Payload[i])
+				}
+			}
+
+			return TRUE
+		}
+	}
+
+	expected := Eval(pattern, env)
+	if isAbrupt(expected) { return expected }
+
+	return nativeBoolToBooleanObject(object.Equal(expected, subject))
+}
+
+func newInstance(structObj *object.Struct, args []object.Object) object.Object {
+	if len(args) != len(structObj.Fields) {
+		return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), len(structObj.Fields))
+	}
+
+	fields := make(map[string]object.Object, len(args))
+	for i, name := range structObj.Fields {
+		fields[name] = args[i]
+	}
+
+	return &object.Instance{Struct: structObj, Fields: fields}
+}
+
+/*
+	Runs the try block and, if it evaluates to an error, hands it over to the
+	catch block as an *object.Exception. The finally block always runs; its
+	result is discarded unless it fails or returns itself.
+*/
+func evalTryExpression(node *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(node.Block, env)
+
+	// Errors of hitting the limits of a sandbox are meant to stop the program
+	if err, ok := result.(*object.Error); ok && node.Catch != nil && err.Kind != "LimitError" {
+		catchEnv := object.NewScope(env, 1)
+
+		if node.CatchParam != nil {
+			bind(catchEnv, node.CatchParam, &object.Exception{Error: err})
+		}
+
+		result = Eval(node.Catch, catchEnv)
+	}
+
+	if node.Finally != nil {
+		finalResult := Eval(node.Finally, env)
+
+		if finalResult != nil {
+			rt := finalResult.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return finalResult
+			}
+		}
+	}
+
+	if result == nil { return NIL }
+
+	return result
+}
+
+/*
+	Blocks until one of the select cases can go on, picking one at random if
+	several can, then evaluates its body with the received value bound. The
+	default case, if given, is taken right away when no other case is ready.
+	Receiving from a closed channel yields nil.
+*/
+func evalSelectExpression(node *ast.SelectExpression, env *object.Environment) object.Object {
+	cases := make([]reflect.SelectCase, len(node.Cases))
+
+	for i, c := range node.Cases {
+		if c.Operation == nil {
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectDefault}
+			continue
+		}
+
+		operation := c.Operation.Function.String()
+		args := evalExpressions(c.Operation.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) { return args[0] }
+
+		want := map[string]int{"recv": 1, "send": 2}[operation]
+		if len(args) != want {
+			return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments to %s. got=%d, want=%d", operation, len(args), want)
+		}
+
+		channel, ok := args[0].(*object.Channel)
+		if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `%s` must be CHANNEL, got %s", operation, args[0].Type()) }
+
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel.Ch)}
+		if operation == "send" {
+			cases[i].Dir = reflect.SelectSend
+			cases[i].Send = reflect.ValueOf(&args[1]).Elem()
+		}
+	}
+
+	chosen, received, err := selectCase(cases)
+	if err != nil { return err }
+
+	selected := node.Cases[chosen]
+	caseEnv := object.NewScope(env, 1)
+
+	if selected.Binding != nil {
+		bind(caseEnv, selected.Binding, received)
+	}
+
+	result := Eval(selected.Body, caseEnv)
+	if result == nil { return NIL }
+
+	return result
+}
+
+func selectCase(cases []reflect.SelectCase) (chosen int, received object.Object, err *object.Error) {
+	defer func() {
+		if recover() != nil { err = newKindError("RuntimeError", "closed_channel", nil, "send on closed channel") }
+	}()
+
+	chosen, value, ok := reflect.Select(cases)
+
+	received = NIL
+	if ok { received = value.Interface().(object.Object) }
+
+	return chosen, received, nil
+}
+
+/*
+	Unwraps an ok result, or returns an err result early from the enclosing
+	function by wrapping it as a return value, which applyFunction unwraps.
+*/
+func evalPropagateExpression(value object.Object) object.Object {
+	result, ok := asResult(value)
+	if !ok { return newKindError("TypeError", "not_result", []object.Object{value}, "operator ? not supported: %s", value.Type()) }
+
+	if result.Variant == errVariant {
+		return &object.ReturnValue{Value: result}
+	}
+
+	return result.Payload[0]
+}
+
+/*
+	Builds the error raised by a throw statement. Throwing a caught exception
+	raises its original error again, anything else becomes a UserError.
+*/
+func throwValue(val object.Object) object.Object {
+	switch val := val.(type) {
+	case *object.Exception:
+		// Rethrown, the error unwinds calls of its own from where it's thrown
+		rethrown := *val.Error
+		rethrown.Line, rethrown.Column, rethrown.Trace = 0, 0, nil
+		return &rethrown
+	case *object.String:
+		return &object.Error{Message: val.Value, Kind: "UserError", Code: "thrown", Value: val}
+	default:
+		return &object.Error{Message: val.Inspect(), Kind: "UserError", Code: "thrown", Value: val}
+	}
+}
+
+func evalRangeExpression(node *ast.RangeExpression, env *object.Environment) object.Object {
+	start := Eval(node.Start, env)
+	if isAbrupt(start) { return start }
+
+	end := Eval(node.End, env)
+	if isAbrupt(end) { return end }
+
+	var step object.Object = &object.Integer{Value: 1}
+	if node.Step != nil {
+		step = Eval(node.Step, env)
+		if isAbrupt(step) { return step }
+	}
+
+	for _, bound := range []object.Object{start, end, step} {
+		if bound.Type() != object.INTEGER_OBJ {
+			return newKindError("TypeError", "invalid_range", []object.Object{bound}, "range bounds must be INTEGER, got %s", bound.Type())
+		}
+
+		if _, ok := bound.(*object.Integer); !ok {
+			return newKindError("OverflowError", "integer_overflow", []object.Object{bound}, "range bound out of range: %s", bound.Inspect())
+		}
+	}
+
+	stepVal := step.(*object.Integer).Value
+	if stepVal == 0 { return newKindError("RuntimeError", "invalid_range", nil, "range step cannot be zero") }
+
+	rng := &object.Range{
+		Start: start.(*object.Integer).Value,
+		End: end.(*object.Integer).Value,
+		Step: stepVal,
+		Inclusive: node.Inclusive,
+	}
+
+	if _, ok := rng.Length(); !ok {
+		return newKindError("RangeError", "range_too_long", []object.Object{rng}, "range too long: %s has more than %d elements", rng.Inspect(), int64(math.MaxInt64))
+	}
+
+	return rng
+}
+
+/*
+	Runs the loop body once per element of the iterable, each time in a fresh
+	enclosed environment holding the loop variable. A return or an error inside
+	the body stops the loop and is handed back to the enclosing block.
+*/
+func evalForExpression(node *ast.ForExpression, env *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isAbrupt(iterable) { return iterable }
+
+	it, ok := iterable.(object.Iterable)
+	if !ok { return newKindError("TypeError", "not_iterable", []object.Object{iterable}, "for-in not supported: %s", iterable.Type()) }
+
+	iterator := it.Iterator()
+
+	for {
+		element, ok := iterator.Next()
+		if !ok { break }
+		if isAbrupt(element) { return element }
+
+		loopEnv := object.NewScope(env, 1)
+		bind(loopEnv, node.Variable, element)
+
+		result := Eval(node.Body, loopEnv)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+
+	return NIL
+}
+
+func evalInExpression(left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Range:
+		integer, ok := left.(*object.Integer)
+		if !ok { return FALSE }
+
+		return nativeBoolToBooleanObject(right.Contains(integer.Value))
+	case *object.Array:
+		for _, element := range right.Elements {
+			if object.Equal(left, element) { return TRUE }
+		}
+
+		return FALSE
+	case *object.String:
+		str, ok := left.(*object.String)
+		if !ok { return newKindError("TypeError", "type_mismatch", []object.Object{left, right}, "type mismatch: %s in %s", left.Type(), right.Type()) }
+
+		return nativeBoolToBooleanObject(strings.Contains(right.Value, str.Value))
+	case *object.Hash:
+		if _, ok := object.HashKeyOf(left); !ok {
+			return newKindError("KeyError", "unhashable_key", []object.Object{left}, "unusable as hash key: %s", left.Type())
+		}
+
+		_, ok := right.Get(left)
+
+		return nativeBoolToBooleanObject(ok)
+	default:
+		return newKindError("TypeError", "unknown_operator", []object.Object{left, right}, "unknown operator: %s in %s", left.Type(), right.Type())
+	}
 }
 
 func evalExclamationOperatorExpression(right object.Object) object.Object {
@@ -169,15 +799,33 @@ func evalExclamationOperatorExpression(right object.Object) object.Object {
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newKindError("TypeError", "unknown_operator", []object.Object{right}, "unknown operator: -%s", right.Type())
+	}
+
+	integer, ok := right.(*object.Integer)
+	if !ok || integer.Value == math.MinInt64 {
+		value, _ := object.BigValue(right)
+		return object.IntegerOf(new(big.Int).Neg(value))
 	}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return &object.Integer{Value: -integer.Value}
+}
+
+/*
+	Fails with an OverflowError if obj is an integer too large for 64 bits and
+	env takes integers to be 64 bits (see object.Environment.StrictIntegers).
+*/
+func checkInteger(obj object.Object, env *object.Environment) object.Object {
+	integer, ok := obj.(*object.BigInteger)
+	if !ok || !env.StrictIntegers() { return obj }
+
+	return newKindError("OverflowError", "integer_overflow", []object.Object{integer}, "integer overflow: %s does not fit in 64 bits", integer.Inspect())
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
+	case operator == "in":
+		return evalInExpression(left, right)
 	/**
 		Need to be higher in the switch branches, because can't assure correct 
 		pointer comparasion for Integers.
@@ -189,13 +837,15 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case (left.Type() == object.STRING_OBJ || right.Type() == object.STRING_OBJ) && (left.Type() == object.INTEGER_OBJ || right.Type() == object.INTEGER_OBJ):
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+	case isOrdering(operator) && left.Type() == right.Type():
+		return evalOrderingExpression(operator, left, right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError("TypeError", "type_mismatch", []object.Object{left, right}, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError("TypeError", "unknown_operator", []object.Object{left, right}, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -203,65 +853,153 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 
 	switch operator {
 	case "+":
-		leftVal := left.(*object.String).Value
-		rightVal := right.(*object.String).Value
+		leftVal, ok := left.(*object.String)
+		if !ok { break }
 
-		return &object.String{Value: leftVal + rightVal}
+		rightVal, ok := right.(*object.String)
+		if !ok { break }
+
+		return &object.String{Value: leftVal.Value + rightVal.Value}
 	case "*":
 		var out strings.Builder
 		var index int64
 		var edge int64
-		var str string
 		
-		integer, ok := left.(*object.Integer)
-		if ok {
-			edge = integer.Value
-			str = right.(*object.String).Value
-		} else {
-			edge = right.(*object.Integer).Value
-			str = left.(*object.String).Value
+		if left.Type() == right.Type() { break }
+
+		count, str := left, right
+		if left.Type() == object.STRING_OBJ { count, str = right, left }
+
+		integer, ok := count.(*object.Integer)
+		if !ok {
+			value, _ := object.BigValue(count)
+			if value.Sign() < 0 { return &object.String{Value: ""} }
+
+			return newKindError("OverflowError", "integer_overflow", []object.Object{left, right}, "string repetition count out of range: %s", count.Inspect())
 		}
 
+		edge = integer.Value
+
 		for index < edge {
-			out.WriteString(str)
+			out.WriteString(str.(*object.String).Value)
 			index++
 		}
 
 		return &object.String{Value: out.String()}
+	case "==":
+		return nativeBoolToBooleanObject(object.Equal(left, right))
+	case "!=":
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+	case "<", ">", "<=", ">=":
+		if left.Type() == right.Type() { return evalOrderingExpression(operator, left, right) }
+	}
+
+	if left.Type() != right.Type() {
+		return newKindError("TypeError", "type_mismatch", []object.Object{left, right}, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	}
-	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+
+	return newKindError("TypeError", "unknown_operator", []object.Object{left, right}, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 }
 
+/*
+	Integers are 64 bits until a result overflows, which is then computed again
+	with big integers (see object.BigInteger).
+*/
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+	l, lok := left.(*object.Integer)
+	r, rok := right.(*object.Integer)
+	if !lok || !rok { return evalBigIntegerInfixExpression(operator, left, right) }
+
+	leftVal, rightVal := l.Value, r.Value
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		sum, ok := object.AddInt64(leftVal, rightVal)
+		if !ok { break }
+		return &object.Integer{Value: sum}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		diff, ok := object.SubInt64(leftVal, rightVal)
+		if !ok { break }
+		return &object.Integer{Value: diff}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		product, ok := object.MulInt64(leftVal, rightVal)
+		if !ok { break }
+		return &object.Integer{Value: product}
 	case "/":
-		if right == ZERO { return newError("division by zero: it is impossible to divide by zero") }
-		return &object.Integer{Value: leftVal / rightVal}
+		if rightVal == 0 { return newKindError("ZeroDivision", "division_by_zero", []object.Object{left, right}, "division by zero: it is impossible to divide by zero") }
+
+		quotient, ok := object.DivInt64(leftVal, rightVal)
+		if !ok { break }
+		return &object.Integer{Value: quotient}
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError("TypeError", "unknown_operator", []object.Object{left, right}, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	// The result overflowed
+	return evalBigIntegerInfixExpression(operator, left, right)
+}
+
+func evalBigIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal, _ := object.BigValue(left)
+	rightVal, _ := object.BigValue(right)
+
+	switch operator {
+	case "+":
+		return object.IntegerOf(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return object.IntegerOf(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return object.IntegerOf(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 { return newKindError("ZeroDivision", "division_by_zero", []object.Object{left, right}, "division by zero: it is impossible to divide by zero") }
+		return object.IntegerOf(new(big.Int).Quo(leftVal, rightVal))
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	case ">", "<", ">=", "<=":
+		return evalOrderingExpression(operator, left, right)
+	default:
+		return newKindError("TypeError", "unknown_operator", []object.Object{left, right}, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isOrdering(operator string) bool {
+	return operator == "<" || operator == ">" || operator == "<=" || operator == ">="
+}
+
+// Orders left against right with object.Compare, for values of the same type.
+func evalOrderingExpression(operator string, left, right object.Object) object.Object {
+	order, ok := object.Compare(left, right)
+	if !ok { return newKindError("TypeError", "unknown_operator", []object.Object{left, right}, "unknown operator: %s %s %s", left.Type(), operator, right.Type()) }
+
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(order < 0)
+	case ">":
+		return nativeBoolToBooleanObject(order > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(order <= 0)
+	default:
+		return nativeBoolToBooleanObject(order >= 0)
 	}
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
-	if isError(condition) { return condition }
+	if isAbrupt(condition) { return condition }
 
 	if truthy(condition) {
 		return Eval(ie.Consequence, env)
@@ -278,28 +1016,44 @@ func evalIndexExpression(left, index object.Object) object.Object {
 			return evalArrayIndexExpression(left, index)
 		case left.Type() == object.HASH_OBJ:
 			return evalHashIndexExpression(left, index)
+		case left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ:
+			return evalRangeIndexExpression(left, index)
 		default:
-			return newError("index operator not supported: %s", index.Type())
+			return newKindError("IndexError", "unsupported_index", []object.Object{left, index}, "index operator not supported: %s", index.Type())
 	}
 }
 
 func evalHashIndexExpression(left, index object.Object) object.Object {
 	hashObj := left.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
-	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+	if _, ok := object.HashKeyOf(index); !ok {
+		return newKindError("KeyError", "unhashable_key", []object.Object{index}, "unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObj.Pairs[key.HashKey()]
+	value, ok := hashObj.Get(index)
 	if !ok { return NIL }
 
-	return pair.Value
+	return value
+}
+
+// Indexes too large for 64 bits are out of range of anything.
+func evalRangeIndexExpression(rng, index object.Object) object.Object {
+	integer, ok := index.(*object.Integer)
+	if !ok { return NIL }
+
+	value, ok := rng.(*object.Range).At(integer.Value)
+	if !ok { return NIL }
+
+	return &object.Integer{Value: value}
 }
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
+
+	integer, ok := index.(*object.Integer)
+	if !ok { return NIL }
+
+	idx := integer.Value
 	max := int64(len(arrayObject.Elements) - 1)
 
 	if idx < 0 || idx > max { return NIL }
@@ -324,26 +1078,74 @@ func evalBlockStatement(bs *ast.BlockStatement, env *object.Environment) object.
 	return result
 }
 
+/*
+	Looks the identifier up where the resolver bound it, or by name through the
+	whole chain of environments if it wasn't resolved.
+*/
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if node.TokenLiteral() == "nil" { return NIL }
 
-	val, ok := env.Get(node.Value)
+	switch {
+	case node.Binding == nil:
+		return lookupName(node.Value, env)
+	case node.Binding.Slot == ast.Global:
+		return lookupName(node.Value, env.Ancestor(node.Binding.Depth))
+	}
+
+	val, ok := env.GetSlot(node.Binding.Depth, node.Binding.Slot)
+	if !ok { return newKindError("NameError", "identifier_not_found", nil, "identifier not found: " + node.Value) }
 
-	if builtin, ok := builtins[node.Value]; ok {
+	return val
+}
+
+// Resolves name in env, falling back on the builtins of env, which bindings shadow.
+func lookupName(name string, env *object.Environment) object.Object {
+	if val, ok := env.Get(name); ok {
+		return val
+	}
+
+	if builtin, ok := lookupBuiltin(name, env); ok {
 		return builtin
 	}
 
-	if !ok { return newError("identifier not found: " + node.Value)}
+	return newKindError("NameError", "identifier_not_found", nil, "identifier not found: " + name)
+}
 
-	return val
+// Looks name up among the builtins of env, the default ones unless it was given some.
+func lookupBuiltin(name string, env *object.Environment) (*object.Builtin, bool) {
+	if registry := env.Builtins(); registry != nil {
+		return registry.Get(name)
+	}
+
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// Binds value to the name ident declares in env, in its slot once resolved.
+func bind(env *object.Environment, ident *ast.Identifier, value object.Object) {
+	if ident.Binding == nil || ident.Binding.Slot == ast.Global {
+		env.Set(ident.Value, value)
+		return
+	}
+
+	env.SetSlot(ident.Binding.Slot, value)
 }
 
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadElement); ok {
+			elements := evalSpreadElement(spread, env)
+			if len(elements) == 1 && isAbrupt(elements[0]) {
+				return elements
+			}
+			result = append(result, elements...)
+			continue
+		}
+
 		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		if isAbrupt(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -352,24 +1154,154 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func evalSpreadElement(spread *ast.SpreadElement, env *object.Environment) []object.Object {
+	value := Eval(spread.Value, env)
+	if isAbrupt(value) { return []object.Object{value} }
+
+	switch value := value.(type) {
+	case *object.Array:
+		return value.Elements
+	case *object.Range, *object.Generator:
+		if err := allocate(env, iterableAllocation(value)); err != nil { return []object.Object{err} }
+
+		return iterate(value.(object.Iterable))
+	default:
+		return []object.Object{newKindError("TypeError", "not_spreadable", []object.Object{value}, "spread operator not supported: %s", value.Type())}
+	}
+}
+
+/*
+	Collects every element of an iterable into a slice. If producing an element
+	fails (i.e. inside a generator), the error is returned as the only element.
+*/
+func iterate(iterable object.Iterable) []object.Object {
+	elements := []object.Object{}
+	iterator := iterable.Iterator()
+
+	for {
+		element, ok := iterator.Next()
+		if !ok { return elements }
+		if isAbrupt(element) { return []object.Object{element} }
+
+		elements = append(elements, element)
+	}
+}
+
+/*
+	Calls fn with args. env is the environment the call is made from, whose
+	runtime builtins doing I/O use.
+*/
+func applyFunction(fn object.Object, args []object.Object, env *object.Environment) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		return callFunction(fn, args, env)
 	case *object.Builtin:
+		if fn.RuntimeFn != nil { return fn.RuntimeFn(env.Runtime(), args...) }
+
 		return fn.Fn(args...)
+	case *object.Struct:
+		return newInstance(fn, args)
+	case *object.EnumVariant:
+		return newEnumValue(fn, args)
+	case *object.BoundMethod:
+		return applyFunction(fn.Method, append([]object.Object{fn.Receiver}, args...), env)
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newKindError("TypeError", "not_callable", []object.Object{fn}, "not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+/*
+	Calls fn, then keeps making the tail calls it hands back in the same loop,
+	so tail recursive functions run in constant stack space however deep they
+	go. Tail calls to anything but a dx function are made as regular calls.
+	The calls run with the sandbox of env, the environment fn is called from.
+*/
+func callFunction(fn *object.Function, args []object.Object, env *object.Environment) object.Object {
+	var site token.Token
+	var caller *object.Function // Function making the tail call, whose frame fn replaces
+
+	if sandbox := env.Sandbox(); sandbox != nil {
+		if err := sandbox.Enter(); err != nil { return err }
+		defer sandbox.Leave()
+	}
+
+	for {
+		if len(args) != len(fn.Parameters) {
+			err := withPosition(newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters)), site)
+			if caller != nil { return traced(err, caller) }
+
+			return err
+		}
+
+		extendedEnv := extendFunctionEnv(fn, args, env)
+		if fn.IsGenerator {
+			return object.NewGenerator(extendedEnv, func() object.Object {
+				return finishTailCall(unwrapReturnValue(Eval(fn.Body, extendedEnv)), extendedEnv)
+			})
+		}
+
+		result := unwrapReturnValue(Eval(fn.Body, extendedEnv))
+
+		tail, ok := result.(*object.TailCall)
+		if !ok { return traced(result, fn) }
+
+		site, caller = tail.Token, fn
+		switch next := tail.Function.(type) {
+		case *object.Function:
+			fn, args = next, tail.Arguments
+		case *object.BoundMethod:
+			method, ok := next.Method.(*object.Function)
+			if !ok { return traced(finishTailCall(tail, extendedEnv), fn) }
+
+			fn, args = method, append([]object.Object{next.Receiver}, tail.Arguments...)
+		default:
+			return traced(finishTailCall(tail, extendedEnv), fn)
+		}
+	}
+}
+
+/*
+	Records in the trace of obj, if it's an error, that it unwound a call of fn.
+	The position of the call is set by the caller (see atCallSite).
+*/
+func traced(obj object.Object, fn *object.Function) object.Object {
+	if err, ok := obj.(*object.Error); ok {
+		err.Trace = append(err.Trace, object.Frame{Function: fn.Name})
+	}
+
+	return obj
+}
+
+// Sets the position of the last call the error obj unwound, unless it's known.
+func atCallSite(obj object.Object, tok token.Token) object.Object {
+	if err, ok := obj.(*object.Error); ok && len(err.Trace) > 0 {
+		if frame := &err.Trace[len(err.Trace)-1]; frame.Line == 0 {
+			frame.Line, frame.Column = tok.Line, tok.Column
+		}
+	}
+
+	return obj
+}
+
+/*
+	Makes the call obj stands for if it's a tail call, from env, or returns obj
+	as is.
+*/
+func finishTailCall(obj object.Object, env *object.Environment) object.Object {
+	if tail, ok := obj.(*object.TailCall); ok {
+		return atCallSite(withPosition(applyFunction(tail.Function, tail.Arguments, env), tail.Token), tail.Token)
+	}
+
+	return obj
+}
+
+// Returns the environment of a call to fn from caller, running with the caller's sandbox.
+func extendFunctionEnv(fn *object.Function, args []object.Object, caller *object.Environment) *object.Environment {
+	env := object.NewFrame(fn.Env, len(fn.Parameters))
+	env.SetSandbox(caller.Sandbox())
 
 	for paramId, param := range fn.Parameters {
-		env.Set(param.Value, args[paramId])
+		bind(env, param, args[paramId])
 	}
 
 	return env
@@ -403,11 +1335,35 @@ func nativeBoolToBooleanObject(bol bool) *object.Boolean {
 }
 
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return newKindError("RuntimeError", "runtime_error", nil, format, a...)
 }
 
-func isError(obj object.Object) bool {
-	if obj != nil { return obj.Type() == object.ERROR_OBJ }
+// Returns an error of kind, identified by code, about the offending values.
+func newKindError(kind, code string, values []object.Object, format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: kind, Code: code, Values: values}
+}
+
+/*
+	Tags an error with the position of the token that raised it. Errors that
+	already carry a position keep it, so the innermost expression wins.
+*/
+func withPosition(obj object.Object, tok token.Token) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+		err.Line, err.Column = tok.Line, tok.Column
+	}
+
+	return obj
+}
+
+/*
+	Reports whether obj must cut the evaluation of the enclosing expression
+	short: errors, and return values raised mid-expression by the ? operator.
+*/
+func isAbrupt(obj object.Object) bool {
+	if obj != nil {
+		rt := obj.Type()
+		return rt == object.ERROR_OBJ || rt == object.RETURN_VALUE_OBJ
+	}
 
 	return false
 }
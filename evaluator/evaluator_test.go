@@ -1,10 +1,17 @@
 package evaluator
 
 import (
+	"context"
+	"dux/ast"
 	"dux/lexer"
 	"dux/object"
 	"dux/parser"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestHashIndexExpressions(t *testing.T) {
@@ -68,26 +75,83 @@ func TestHashLiterals(t *testing.T) {
 		t.Fatalf("Eval didn't return object.Hash. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := map[uint64]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
+	// Pairs keep the order of the literal
+	expected := []struct{
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for i, pair := range result.Pairs() {
+		if pair.Key.Inspect() != expected[i].key.Inspect() {
+			t.Errorf("wrong key at %d. want=%s, got=%s", i, expected[i].key.Inspect(), pair.Key.Inspect())
+		}
+
+		value, ok := result.Get(expected[i].key)
 		if !ok {
 			t.Errorf("no pair for the given key")
 		}
 
-		testIntegerObject(t, pair.Value, expectedValue)
+		testIntegerObject(t, value, expected[i].value)
+	}
+}
+
+func TestHashKeys(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{`{1: "a", true: "b"}`, `{1: "a", true: "b"}`},
+		{`let h = {1: "a", true: "b", "1": "c"}; [h[1], h[true], h["1"]]`, `["a", "b", "c"]`},
+		{`{0: "zero", false: "no"}[false]`, `"no"`},
+		{`1 in {true: 1}`, "false"},
+		{`{[1, 2]: "pair", [2, 1]: "reversed"}[[1, 2]]`, `"pair"`},
+		{`let h = {[1, [2, "x"]]: 1}; [1, [2, "x"]] in h`, "true"},
+		{`{[]: "empty"}[[]]`, `"empty"`},
+		{`{[1]: 1, [1]: 2}`, "{[1]: 2}"},
+		{`struct P { x, y } let h = {P(1, 2): "p"}; [h[P(1, 2)], h[P(2, 1)]]`, `["p", nil]`},
+		{`struct P { x } struct Q { x } {P(1): "p"}[Q(1)]`, "nil"},
+		{`enum Shape { Dot(p) } {Shape.Dot([1, 2]): 1}[Shape.Dot([1, 2])]`, "1"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, `{"b": 1, "a": 2, "c": 3}`},
+		{`{3: "x", 1: "y", 2: "z"}`, `{3: "x", 1: "y", 2: "z"}`},
+		{`{"a": 1, "b": 2, "a": 3}`, `{"a": 3, "b": 2}`},
+		{`let h = {"z": 1, "y": 2}; {...h, "x": 3, "z": 4}`, `{"z": 4, "y": 2, "x": 3}`},
+		{`{"a": 1, ...{"b": 2}}`, `{"a": 1, "b": 2}`},
+		{`{"a": 1, ...{"a": 5}}`, `{"a": 5}`},
+		{`to_array({"k": 1, "j": 2, "i": 3})`, `["k", "j", "i"]`},
+		{`{"c": 1, "a": 2, "b": 3} == {"a": 2, "b": 3, "c": 1}`, "true"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong hash for %q. want=%s, got=%s", tc.input, tc.expected, evaluated.Inspect())
+		}
 	}
 }
 
@@ -184,6 +248,15 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`head([])`, "nil"},
 		{`push([], 5)`, "[5]"},
 		{`push([1], true)`, "[1, true]"},
+		{`sort([3, 1, 2])`, "[1, 2, 3]"},
+		{`sort(["pear", "apple", "fig"])`, `["apple", "fig", "pear"]`},
+		{`sort([[2, 1], [1, 2], [1]])`, "[[1], [1, 2], [2, 1]]"},
+		{`let xs = [2, 1]; sort(xs); xs`, "[2, 1]"},
+		{`sort([1, "a"])`, "cannot compare STRING with INTEGER"},
+		{`contains([1, [2, 3]], [2, 3])`, true},
+		{`contains(["a", "b"], "c")`, false},
+		{`contains("haystack", "st")`, true},
+		{`contains({"k": 1}, "k")`, true},
 	}
 
 	for _, tc := range tests {
@@ -192,6 +265,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		switch expected := tc.expected.(type) {
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
 		case string:
 			switch obj := evaluated.(type) {
 			case *object.String:
@@ -264,6 +339,30 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{`"abc" == "abc"`, true},
+		{`"abc" != "abc"`, false},
+		{`"abc" == "abd"`, false},
+		{`"a" == 1`, false},
+		{`"apple" < "banana"`, true},
+		{`"b" > "abc"`, true},
+		{`"ab" < "abc"`, true},
+		{`"abc" <= "abc"`, true},
+		{`"abc" >= "abd"`, false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[1, 2] != [2, 1]", true},
+		{`[1, "a"] == [1, "a"]`, true},
+		{"[1, 2] < [1, 3]", true},
+		{"[1, 2] < [1]", false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{} == {}`, true},
+		{`[1, 2] == {}`, false},
 	}
 
 	for _, tc := range tests {
@@ -445,13 +544,982 @@ func TestStringMultiplication(t *testing.T) {
 	}
 }
 
+func TestSpreadOperator(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2]; let b = [3]; [...a, ...b, 4]", "[1, 2, 3, 4]"},
+		{"[...[], 1]", "[1]"},
+		{"let add = fn(x, y) { x + y }; let args = [2, 3]; add(...args)", 5},
+		{"let add = fn(x, y) { x + y }; add(1, ...[2])", 3},
+		{`let defaults = {"fee": 1, "tax": 3}; {...defaults, "fee": 2}["fee"]`, 2},
+		{`let defaults = {"fee": 1, "tax": 3}; {...defaults, "fee": 2}["tax"]`, 3},
+		{"[...1]", "spread operator not supported: INTEGER"},
+		{`{...[1]}`, "spread operator not supported in hash literal: ARRAY"},
+		{"let add = fn(x, y) { x + y }; add(...[1])", "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"len(0..10)", 10},
+		{"len(0..=10)", 11},
+		{"len(0..10 step 3)", 4},
+		{"len(10..0)", 0},
+		{"(0..10 step 2)[3]", 6},
+		{"(0..10)[10]", nil},
+		{"let r = 0..1000000000; r[999999999]", 999999999},
+		{"to_array(0..5)", "[0, 1, 2, 3, 4]"},
+		{"to_array(5..=1 step -2)", "[5, 3, 1]"},
+		{"[...1..4, 9]", "[1, 2, 3, 9]"},
+		{"(0..5)", "0..5"},
+		{"3 in 0..5", true},
+		{"5 in 0..5", false},
+		{"4 in 0..10 step 2", true},
+		{"5 in 0..10 step 2", false},
+		{"2 in [1, 2, 3]", true},
+		{"4 in [1, 2, 3]", false},
+		{`"b" in ["a", "b"]`, true},
+		{`"ell" in "hello"`, true},
+		{`"x" in {"x": 1}`, true},
+		{`"y" in {"x": 1}`, false},
+		{`0.."a"`, "range bounds must be INTEGER, got STRING"},
+		{"0..10 step 0", "range step cannot be zero"},
+		{"len(0..9223372036854775807)", 9223372036854775807},
+		{"len(0..=9223372036854775806)", 9223372036854775807},
+		{"len(-5000000000000000000..5000000000000000000 step 2)", 5000000000000000000},
+		{"len(9223372036854775807..-9223372036854775807 step -2)", 9223372036854775807},
+		{"len(9223372036854775807..=-9223372036854775807 step -9223372036854775807)", 3},
+		{"9223372036854775806 in 0..9223372036854775807", true},
+		{"9223372036854775807 in 0..9223372036854775807", false},
+		{"-9223372036854775805 in 9223372036854775807..-9223372036854775807 step -2", true},
+		{"-9223372036854775807 in 9223372036854775807..-9223372036854775807 step -2", false},
+		{"(-9000000000000000000..=9000000000000000000 step 3000000000000000000)[6]", 9000000000000000000},
+		{"to_array(-9000000000000000000..=9000000000000000000 step 6000000000000000000)", "[-9000000000000000000, -3000000000000000000, 3000000000000000000, 9000000000000000000]"},
+		{"let below = fn(r, x) { for (i in r) { if (i < x) { return i } } }; below(9223372036854775807..=9223372036854775805 step -1, 9223372036854775806)", 9223372036854775805},
+		{"0..=9223372036854775807", "range too long: 0..=9223372036854775807 has more than 9223372036854775807 elements"},
+		{"-5000000000000000000..5000000000000000000", "range too long: -5000000000000000000..5000000000000000000 has more than 9223372036854775807 elements"},
+		{"9223372036854775807..=-1 step -1", "range too long: 9223372036854775807..=-1 step -1 has more than 9223372036854775807 elements"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestForExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"let firstEven = fn(r) { for (i in r) { if (i / 2 * 2 == i) { return i } } }; firstEven(7..20)", 8},
+		{"let find = fn(xs, x) { for (e in xs) { if (e == x) { return true } }; false }; find([1, 2, 3], 2)", true},
+		{"let find = fn(xs, x) { for (e in xs) { if (e == x) { return true } }; false }; find(0..100000, -1)", false},
+		{`let any = fn(s, chars) { for (c in s) { if (c in chars) { return true } }; false }; any("abc", "zc")`, true},
+		{`let has = fn(s, x) { for (c in s) { if (c == x) { return true } }; false }; has("héllo", "é")`, true},
+		{`to_array("né")`, `["n", "é"]`},
+		{"for (i in 0..3) { i }", nil},
+		{"for (i in 0..3) { i + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"for (i in 5) { i }", "for-in not supported: INTEGER"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestStructs(t *testing.T) {
+	account := `
+		struct Account {
+			id, balance
+			fn deposit(self, amount) { Account(self.id, self.balance + amount) }
+			fn owner(self) { "owner-" * self.id }
+		}
+		let acct = Account(2, 100);
+	`
+
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{account + "acct.balance", 100},
+		{account + "acct.deposit(50).balance", 150},
+		{account + "acct.deposit(50).deposit(25).balance", 175},
+		{account + "let deposit = acct.deposit; deposit(1).balance", 101},
+		{account + "type(acct)", "Account"},
+		{account + "type(Account)", "STRUCT"},
+		{account + "acct.owner()", "owner-owner-"},
+		{account + "acct", "Account{id: 2, balance: 100}"},
+		{account + "Account", "struct Account { id, balance }"},
+		{account + "acct.missing", "unknown member missing on Account"},
+		{account + "Account(1)", "wrong number of arguments. got=1, want=2"},
+		{"let x = 5; x.y", "unknown member y on INTEGER"},
+		{"struct Empty {} type(Empty())", "Empty"},
+		{`struct Point { x, y fn inspect(self) { "<point>" } } Point(1, 2)`, "<point>"},
+		{"struct INTEGER { v } (fn(x) { x + 1 })(INTEGER(1))", "INTEGER is the name of a built-in type"},
+		{`struct ERROR { message } let e = ERROR("x"); e`, "ERROR is the name of a built-in type"},
+		{"struct Integer { v } type(Integer(1))", "Integer"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestEnums(t *testing.T) {
+	status := `
+		enum Status { Pending, Settled(amount), Failed(reason) }
+		let describe = fn(s) {
+			match (s) {
+				Pending => "pending",
+				Settled(amount) => "settled " * amount,
+				Status.Failed(_) => "failed",
+			}
+		};
+	`
+
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"enum STRING { A } STRING.A", "STRING is the name of a built-in type"},
+		{status + "Status.Pending", "Status.Pending"},
+		{status + "Status.Settled(10)", "Status.Settled(10)"},
+		{status + "Status.Settled", "Status.Settled"},
+		{status + "type(Status.Failed(1))", "Status"},
+		{status + "Status.Settled(10).amount", 10},
+		{status + "Status.Pending == Status.Pending", true},
+		{status + "Status.Settled(10) == Status.Settled(10)", true},
+		{status + "Status.Settled(10) == Status.Settled(11)", false},
+		{status + "Status.Settled(10) != Status.Failed(10)", true},
+		{status + "Status.Pending in [Status.Settled(1), Status.Pending]", true},
+		{status + "{Status.Settled(5): 1, Status.Pending: 2}[Status.Settled(5)]", 1},
+		{status + "{Status.Settled(5): 1, Status.Pending: 2}[Status.Pending]", 2},
+		{status + "describe(Status.Pending)", "pending"},
+		{status + "describe(Status.Settled(2))", "settled settled "},
+		{status + "describe(Status.Failed(1))", "failed"},
+		{status + "match (Status.Pending) { Pending => 1 }", "non-exhaustive match on Status: missing Settled, Failed"},
+		{status + "match (Status.Failed(1)) { Pending => 1, _ => 2 }", 2},
+		{status + "match (Status.Settled(1)) { Settled(a, b) => 1, _ => 2 }", "wrong number of bindings for Status.Settled. got=2, want=1"},
+		{status + "Status.Unknown", "unknown variant Unknown on Status"},
+		{status + "Status.Settled()", "wrong number of arguments. got=0, want=1"},
+		{"match (2) { 1 => 10, 2 => 20, _ => 30 }", 20},
+		{`match ("b") { "a" => 10, _ => 30 }`, 30},
+		{"match (3) { 1 => 10 }", "no match arm for 3"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{"try { 1 + true } catch (e) { 2 }", 2},
+		{"try { 1 + true } catch { 2 }", 2},
+		{"try { 1 + true } catch (e) { e.message }", "type mismatch: INTEGER + BOOLEAN"},
+		{"try { 1 + true } catch (e) { e.kind }", "TypeError"},
+		{"try {\n  1 +\n  true } catch (e) { e.position }", "2:5"},
+		{"try { len(1) } catch (e) { e.message }", "argument to `len` not supported, got INTEGER"},
+		{"try { 1 / 0 } catch (e) { e.message }", "division by zero: it is impossible to divide by zero"},
+		{"try { if (false) { let missing = 1 }; missing } catch (e) { e.line }", 1},
+		{`try { throw "boom" } catch (e) { e.message }`, "boom"},
+		{`try { throw "boom" } catch (e) { e.kind }`, "UserError"},
+		{"try { throw 42 } catch (e) { e.value }", 42},
+		{"try { throw 42 } catch (e) { e.message }", "42"},
+		{`throw "boom"; 1`, "boom"},
+		{`try { try { throw "inner" } catch (e) { throw e } } catch (e) { e.message }`, "inner"},
+		{`try { throw "a" } catch (e) { throw "b" }`, "b"},
+		{"let f = fn() { try { return 1 } finally { 2 } }; f()", 1},
+		{"let f = fn() { try { 1 + true } finally { return 3 } }; f()", 3},
+		{"try { 1 + true } finally { 2 }", "type mismatch: INTEGER + BOOLEAN"},
+		{`let safe = fn(x) { try { 10 / x } catch (e) { -1 } }; [safe(2), safe(0), safe(5)]`, "[5, -1, 2]"},
+		{`"a" + 1`, "type mismatch: STRING + INTEGER"},
+		{`"a" * "b"`, "unknown operator: STRING * STRING"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestResults(t *testing.T) {
+	parse := `
+		let parse = fn(x) { if (x < 0) { err("negative: " * 1) } else { ok(x * 2) } };
+		let total = fn(a, b) { ok(parse(a)? + parse(b)?) };
+	`
+
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"ok(5)", "Result.Ok(5)"},
+		{`err("bad")`, `Result.Err("bad")`},
+		{"type(ok(5))", "Result"},
+		{"is_ok(ok(1))", true},
+		{"is_ok(err(1))", false},
+		{"is_err(err(1))", true},
+		{"unwrap(ok(3))", 3},
+		{"unwrap(err(3))", "unwrap called on Result.Err(3)"},
+		{"unwrap_or(ok(3), 0)", 3},
+		{"unwrap_or(err(3), 0)", 0},
+		{"ok(3).value", 3},
+		{"err(3).error", 3},
+		{"ok(1) == ok(1)", true},
+		{"ok(1) == err(1)", false},
+		{"match (ok(4)) { Ok(v) => v, Err(e) => 0 }", 4},
+		{"match (err(4)) { Ok(v) => v }", "non-exhaustive match on Result: missing Err"},
+		{parse + "total(1, 2)", "Result.Ok(6)"},
+		{parse + "total(-1, 2)", `Result.Err("negative: ")`},
+		{parse + "total(1, -2)", `Result.Err("negative: ")`},
+		{parse + "let f = fn(xs) { for (x in xs) { parse(x)? }; ok(len(xs)) }; f([1, -1])", `Result.Err("negative: ")`},
+		{parse + "let f = fn(xs) { for (x in xs) { parse(x)? }; ok(len(xs)) }; f([1, 2])", "Result.Ok(2)"},
+		{parse + "let v = parse(-5)?; 10", `Result.Err("negative: ")`},
+		{"let f = fn() { 5? }; f()", "operator ? not supported: INTEGER"},
+		{"is_ok(5)", "invalid argument INTEGER to 'is_ok', must be Result"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	naturals := "let naturals = fn() { for (i in 0..1000000000) { yield i } };"
+	take := "let take = fn(g, n) { if (n == 0) { [] } else { let v = next(g); [v, ...take(g, n - 1)] } };"
+
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"let g = fn() { yield 1; yield 2; }; to_array(g())", "[1, 2]"},
+		{"let g = fn(n) { yield n; yield n * 2; }; [...g(3), 0]", "[3, 6, 0]"},
+		{naturals + "let g = naturals(); next(g); next(g); next(g)", 2},
+		{naturals + take + "take(naturals(), 4)", "[0, 1, 2, 3]"},
+		{naturals + take + "let evens = fn(g) { for (x in g) { if (x / 2 * 2 == x) { yield x } } }; take(evens(naturals()), 3)", "[0, 2, 4]"},
+		{"let g = fn() { yield 1 }(); next(g); next(g)", "nil"},
+		{"let g = fn() { yield 1 }(); next(g); next(g); next(g)", "nil"},
+		{"let g = fn() { yield 1; return 5; yield 2; }; to_array(g())", "[1]"},
+		{"let g = fn() { let x = 10; yield x; let y = x + 1; yield y; yield x + y; }; to_array(g())", "[10, 11, 21]"},
+		{"let g = fn() { yield 1; 1 + \"a\" }; to_array(g())", "type mismatch: INTEGER + STRING"},
+		{"let g = fn() { yield 1; throw \"boom\" }; for (x in g()) { x }", "boom"},
+		{"let g = fn() { yield 1; throw \"boom\" }(); next(g); try { next(g) } catch (e) { e.message }", "boom"},
+		{"let g = fn() { yield 1; throw \"boom\" }(); next(g); try { next(g) } catch (e) { 0 }; next(g)", "nil"},
+		{"struct Bag { items fn each(self) { for (x in self.items) { yield x * 10 } } } to_array(Bag([1, 2]).each())", "[10, 20]"},
+		{"let g = fn() { yield 1 }; g()", "generator"},
+		{"let g = fn() { yield 1 }; type(g())", "GENERATOR"},
+		{"next([1])", "argument to `next` must be GENERATOR, got ARRAY"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestYieldOutsideGenerator(t *testing.T) {
+	// The parser rejects this program, so it's built by hand as an embedder could
+	program := &ast.Program{Statements: []ast.Statement{
+		&ast.YieldStatement{Value: &ast.IntegerLiteral{Value: 1}},
+		&ast.ExpressionStatement{Expression: &ast.IntegerLiteral{Value: 2}},
+	}}
+
+	err, ok := Eval(program, object.NewEnvironment()).(*object.Error)
+	if !ok || err.Code != "yield_outside_generator" {
+		t.Errorf("expected a yield_outside_generator error, got %v", err)
+	}
+}
+
+func TestYieldFromEscapedClosure(t *testing.T) {
+	input := "let g = fn() { yield fn() { yield 2 } }; let escaped = first(to_array(g())); escaped()"
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	// The parser makes the closure a generator of its own, so it's turned back into a plain function by hand
+	generator := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	closure := generator.Body.Statements[0].(*ast.YieldStatement).Value.(*ast.FunctionLiteral)
+	closure.IsGenerator = false
+
+	env := object.NewEnvironment()
+	Resolve(program, env)
+
+	err, ok := Eval(program, env).(*object.Error)
+	if !ok || err.Code != "yield_outside_generator" {
+		t.Errorf("expected a yield_outside_generator error, got %v", err)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"let c = chan(1); send(c, 5); recv(c)", 5},
+		{"let c = chan(); spawn send(c, 7); recv(c)", 7},
+		{"let double = fn(x) { x * 2 }; wait(spawn double(21))", 42},
+		{"let t = spawn fn() { 1 + true }(); wait(t)", "type mismatch: INTEGER + BOOLEAN"},
+		{`
+			let c = chan(10);
+			let worker = fn(id, out) { send(out, id * 10) };
+			for (i in 0..10) { spawn worker(i, c) };
+			let sum = fn(n) { if (n == 0) { 0 } else { recv(c) + sum(n - 1) } };
+			sum(10)
+		`, 450},
+		{`
+			let square = fn(x) { x * x };
+			let tasks = [spawn square(2), spawn square(3), spawn square(4)];
+			wait(tasks[0]) + wait(tasks[1]) + wait(tasks[2])
+		`, 29},
+		{"let c = chan(2); send(c, 1); send(c, 2); close(c); to_array(c)", "[1, 2]"},
+		{"let c = chan(1); close(c); recv(c)", "nil"},
+		{"let c = chan(1); close(c); send(c, 1)", "send on closed channel"},
+		{"let c = chan(1); close(c); close(c)", "close of closed channel"},
+		{"let c = chan(1); spawn fn() { for (i in 0..3) { send(c, i) }; close(c) }(); for (x in c) { x }; recv(c)", "nil"},
+		{"chan(-1)", "channel size cannot be negative, got -1"},
+		{"recv(1)", "argument to `recv` must be CHANNEL, got INTEGER"},
+		{"wait(1)", "argument to `wait` must be TASK, got INTEGER"},
+		{"let a = chan(1); let b = chan(1); send(b, 2); select { x = recv(a) => x, y = recv(b) => y * 10 }", 20},
+		{"let a = chan(1); select { x = recv(a) => x, _ => 99 }", 99},
+		{"let a = chan(1); select { send(a, 3) => recv(a) }", 3},
+		{"let a = chan(1); close(a); select { x = recv(a) => x }", "nil"},
+		{"let a = chan(1); close(a); select { send(a, 1) => 1 }", "send on closed channel"},
+		{"select { recv(5) => 1 }", "argument to `recv` must be CHANNEL, got INTEGER"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestEnvironmentSharedBetweenTasks(t *testing.T) {
+	input := `
+		let done = chan(20);
+		let worker = fn(i) { let local = i; let shared = i; send(done, local) };
+		for (i in 0..20) { spawn worker(i) };
+		for (i in 0..20) { spawn fn() { let global = i; send(done, global) }() };
+		let sum = fn(n) { if (n == 0) { 0 } else { recv(done) + sum(n - 1) } };
+		sum(40)
+	`
+
+	testIntegerObject(t, testEval(input), 380)
+}
+
+// Waiters trace the error of a task concurrently, so run with -race too.
+func TestTaskErrorSharedBetweenWaiters(t *testing.T) {
+	input := "let fail = fn() { 1 + true };\nlet failing = spawn fail();\nlet waiter = fn() { wait(failing) };\nlet waiters = [spawn waiter(), spawn waiter(), spawn waiter(), spawn waiter()];\nlet settle = fn(ts) { if (len(ts) == 0) { 0 } else { try { wait(first(ts)) } catch (e) { 0 }; settle(tail(ts)) } };\nsettle(waiters);\nwait(failing)"
+
+	err, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("expected an error")
+	}
+
+	expected := "Traceback (most recent call last):\n  at 2:25, in <program>\n  at 1:21, in fail\n"
+	if err.Traceback() != expected {
+		t.Errorf("waiters changed the trace of the task's error. want=%q, got=%q", expected, err.Traceback())
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"let countdown = fn(n) { if (n == 0) { 0 } else { countdown(n - 1) } }; countdown(100000)", 0},
+		{"let countdown = fn(n) { if (n == 0) { return 7; } return countdown(n - 1); }; countdown(100000)", 7},
+		{"let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(100000, 0)", 5000050000},
+		{`
+			let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+			let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+			even(100001)
+		`, false},
+		{`
+			enum Step { Done, More(n) }
+			let step = fn(n) { if (n == 0) { Step.Done } else { Step.More(n - 1) } };
+			let run = fn(n) { match (step(n)) { Done => "done", More(m) => run(m) } };
+			run(100000)
+		`, "done"},
+		{`
+			struct Counter { start fn down(self, n) { if (n == 0) { self.start } else { self.down(n - 1) } } }
+			Counter(3).down(100000)
+		`, 3},
+		{"let f = fn(n) { if (n == 0) { len([1, 2]) } else { f(n - 1) } }; f(10)", 2},
+		{"let f = fn(n) { if (n == 0) { g(1, 2) } else { f(n - 1) } }; let g = fn(x) { x }; f(3)", "wrong number of arguments. got=2, want=1"},
+		{"let f = fn(n) { if (n == 0) { 1 + true } else { f(n - 1) } }; f(100000)", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn(n) { try { if (n == 0) { throw \"bottom\" } else { f(n - 1) } } catch (e) { e.message } }; f(3)", "bottom"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{`"hello".len()`, 5},
+		{`let s = "abc"; s.len()`, 3},
+		{"[1, 2, 3].push(4).tail()", "[2, 3, 4]"},
+		{"let arr = [1]; arr.push(2).push(3).len()", 3},
+		{`"abc".upper()`, "ABC"},
+		{`"ABC".lower()`, "abc"},
+		{`"  a b  ".trim()`, "a b"},
+		{`"a,b,c".split(",")`, `["a", "b", "c"]`},
+		{`"dux".starts_with("du")`, true},
+		{`"dux".ends_with("du")`, false},
+		{`"abc".upper().len()`, 3},
+		{"(0..10).to_array().len()", 10},
+		{"ok(1).is_ok()", true},
+		{"ok(1).unwrap_or(0)", 1},
+		{"struct P { x fn len(self) { 99 } } P(1).len()", 99},
+		{"struct P { x } P([1, 2]).x.len()", 2},
+		{"try { 1 / 0 } catch (e) { e.type() }", "EXCEPTION"},
+		{`"abc".double()`, "unknown member double on STRING"},
+		{"5.upper()", "unknown member upper on INTEGER"},
+		{`"abc".split(1)`, "argument to `split` must be STRING, got INTEGER"},
+		{`"abc".upper(1)`, "wrong number of arguments. got=1, want=0"},
+		{"[1].len(2)", "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestRegistryMethods(t *testing.T) {
+	tests := []struct{
+		input    string
+		registry func() *object.Registry
+		expected interface{}
+	}{
+		{"21.double()", doubling, 42},
+		{"let f = fn(x) { x.double() }; f(4)", doubling, 8},
+		{`"abc".double()`, doubling, "unknown member double on STRING"},
+		{"21.double()", Builtins, "unknown member double on INTEGER"},
+		{`"abc".upper()`, func() *object.Registry { return Builtins().Restrict("len") }, "unknown member upper on STRING"},
+		{`"abc".upper()`, func() *object.Registry { return Builtins().Restrict("upper") }, "ABC"},
+		{"wait(spawn len([1]))", Builtins, 1},
+		{"spawn len([1])", func() *object.Registry { return Builtins().Restrict("len") }, "spawning tasks is disabled"},
+		{"wait(spawn len([1]))", func() *object.Registry { return Builtins().Restrict("len", "wait", "spawn") }, 1},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		env.SetBuiltins(tc.registry())
+
+		evaluated := Eval(parser.New(lexer.New(tc.input)).ParseProgram(), env)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+// Returns the default builtins, with a double method on integers.
+func doubling() *object.Registry {
+	registry := Builtins()
+	registry.SetMethod(object.INTEGER_OBJ, "double", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+	}})
+
+	return registry
+}
+
+func TestScopes(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; let f = fn(x) { x }; f(2)", 2},
+		{"let x = 1; let f = fn() { let y = x; let x = 2; y + x }; f()", 3},
+		{"let x = 1; let f = fn() { let x = x + 1; x }; [f(), x]", "[2, 1]"},
+		{"let a = fn(x) { fn(y) { fn(z) { x + y + z } } }; a(1)(2)(3)", 6},
+		{"let f = fn() { let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5) }; f()", 120},
+		{"let f = fn() { let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; even(10) }; f()", true},
+		{"let f = fn() { let acc = 0; for (i in 1..=4) { let acc = acc + i }; acc }; f()", 0},
+		{"let f = fn(c) { if (c) { let y = 1 } else { let y = 2 }; y }; f(false)", 2},
+		{"let f = fn() { if (false) { let y = 1 }; y }; f()", "identifier not found: y"},
+		{"let f = fn() { try { throw 1 } catch (e) { e.value + 1 } }; f()", 2},
+		{"let f = fn(x) { match (ok(x)) { Ok(v) => v * 2, Err(e) => e } }; f(4)", 8},
+		{"let f = fn() { let c = chan(1); send(c, 3); select { v = recv(c) => v } }; f()", 3},
+		{"let f = fn() { struct P { x fn get(self) { self.x } } P(7).get() }; f()", 7},
+		{"let f = fn() { let c = chan(1); let g = fn() { send(c, 4) }; wait(spawn g()); recv(c) }; f()", 4},
+		{"let f = fn() { g() }; let g = fn() { 5 }; f()", 5},
+		{"let f = fn() { g() }; f()", "identifier not found: g"},
+		{"let f = fn() { let x = 1; x }; x", "identifier not found: x"},
+		{"let len = fn(x) { 42 }; len([1])", 42},
+		{"let f = fn(puts) { puts + 1 }; f(1)", 2},
+		{"let f = fn() { let first = 3; first }; [f(), first([4])]", "[3, 4]"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestConstantExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected interface{}
+	}{
+		{"if (1 - 1) { 1 } else { 2 }", 1},
+		{"if (0) { 1 } else { 2 }", 2},
+		{"let z = 0; if (z) { 1 } else { 2 }", 2},
+		{"let f = fn() { if (true) { return 1; } 2 }; f()", 1},
+		{"let f = fn() { 1; if (false) { 2 } }; f()", "nil"},
+		{"let x = 3; let f = fn() { x }; let x = 4; f()", 4},
+		{"let f = fn() { let g = fn() { x }; let x = 5; g() }; f()", 5},
+		{`"ab" * 3 + "c"`, "abababc"},
+		{"try { 1 / 0 } catch (e) { e.position }", "1:9"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		testExpected(t, evaluated, tc.expected)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	tests := []struct{
+		input    string
+		limits   Limits
+		timeout  time.Duration
+		expected string
+	}{
+		{"let f = fn(n) { f(n + 1) + 1 }; f(0)", Limits{MaxDepth: 100}, 0, "call depth limit exceeded: 100 calls"},
+		{"let f = fn(n) { if (n == 50) { n } else { f(n + 1) + 1 } }; f(0)", Limits{MaxDepth: 100}, 0, ""},
+		{"let f = fn(n) { f(n + 1) }; f(0)", Limits{MaxSteps: 10000}, 0, "step limit exceeded: 10000 steps"},
+		{"let f = fn(n) { f(n + 1) }; f(0)", Limits{}, 50 * time.Millisecond, "evaluation stopped: context deadline exceeded"},
+		{"recv(chan())", Limits{}, 50 * time.Millisecond, "evaluation stopped: context deadline exceeded"},
+		{`"x" * 1000000000`, Limits{MaxMemory: 1 << 20}, 0, "memory limit exceeded: 1048576 bytes"},
+		{`let f = fn(s, n) { if (n == 0) { s } else { f(s + s, n - 1) } }; f("x", 40)`, Limits{MaxMemory: 1 << 20}, 0, "memory limit exceeded: 1048576 bytes"},
+		{"to_array(0..100000000)", Limits{MaxMemory: 1 << 20}, 0, "memory limit exceeded: 1048576 bytes"},
+		{"[...0..100000000]", Limits{MaxMemory: 1 << 20}, 0, "memory limit exceeded: 1048576 bytes"},
+		{"let f = fn(n) { f(n + 1) }; try { f(0) } catch (e) { 1 }", Limits{MaxSteps: 1000}, 0, "step limit exceeded: 1000 steps"},
+		{"let f = fn(n) { f(n + 1) }; try { f(0) } finally { 1 }", Limits{MaxSteps: 1000}, 0, "step limit exceeded: 1000 steps"},
+		{"let t = spawn fn() { let f = fn(n) { f(n + 1) }; f(0) }(); wait(t)", Limits{MaxSteps: 1000}, 0, "step limit exceeded: 1000 steps"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).ParseProgram()
+		env := object.NewEnvironment()
+
+		ctx := context.Background()
+		if tc.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+			defer cancel()
+		}
+
+		evaluated := EvalContext(ctx, program, env, tc.limits)
+
+		err, ok := evaluated.(*object.Error)
+		if tc.expected == "" {
+			if ok { t.Errorf("unexpected error for %q: %s", tc.input, err.Message) }
+			continue
+		}
+
+		if !ok || err.Kind != "LimitError" || err.Message != tc.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%s", tc.input, tc.expected, describe(evaluated))
+		}
+
+		if env.Sandbox() != nil {
+			t.Errorf("sandbox left on the environment of %q", tc.input)
+		}
+	}
+}
+
+func TestSandboxAllocatingBuiltins(t *testing.T) {
+	tests := []struct{
+		input    string
+		builtins func() *object.Registry
+		expected string
+	}{
+		{"to_array(0..100000000)", func() *object.Registry { return Builtins().Restrict("to_array") }, "memory limit exceeded: 1048576 bytes"},
+		{"(0..100000000).to_array()", Builtins, "memory limit exceeded: 1048576 bytes"},
+		{"chan(100000000)", func() *object.Registry { return Builtins().Restrict("chan") }, "memory limit exceeded: 1048576 bytes"},
+		{"grow(100000000)", func() *object.Registry {
+			registry := Builtins().Restrict()
+			registry.Set("grow", &object.Builtin{
+				Fn: func(args ...object.Object) object.Object { return NIL },
+				Allocates: func(args []object.Object) int64 { return args[0].(*object.Integer).Value },
+			})
+			return registry
+		}, "memory limit exceeded: 1048576 bytes"},
+		{"grow(1000)", func() *object.Registry {
+			registry := Builtins().Restrict()
+			registry.Set("grow", &object.Builtin{Fn: func(args ...object.Object) object.Object { return NIL }})
+			return registry
+		}, ""},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.SetBuiltins(tc.builtins())
+
+		evaluated := EvalContext(context.Background(), program, env, Limits{MaxMemory: 1 << 20})
+
+		err, ok := evaluated.(*object.Error)
+		if tc.expected == "" {
+			if ok { t.Errorf("unexpected error for %q: %s", tc.input, err.Message) }
+			continue
+		}
+
+		if !ok || err.Kind != "LimitError" || err.Message != tc.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%s", tc.input, tc.expected, describe(evaluated))
+		}
+	}
+}
+
+func TestSandboxOutlivesDeadline(t *testing.T) {
+	tests := []string{
+		"let spin = fn(n) { tick(); spin(n + 1) }; block(); spin(0)",
+		"block(); for (i in 0..1000000000000) { tick() }",
+		"let f = fn() { block(); for (i in 0..1000000000000) { tick() } }; f()",
+	}
+
+	for _, input := range tests {
+		var ticks atomic.Int64
+		release := make(chan struct{})
+
+		program := parser.New(lexer.New(input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Set("tick", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			ticks.Add(1)
+			return NIL
+		}})
+		env.Set("block", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			<-release
+			return NIL
+		}})
+		Resolve(program, env)
+
+		// The deadline passes while the program is blocked, which it's left running past
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		EvalContext(ctx, program, env, Limits{})
+		cancel()
+		close(release)
+
+		time.Sleep(20 * time.Millisecond)
+		stopped := ticks.Load()
+		time.Sleep(50 * time.Millisecond)
+
+		if ticks.Load() != stopped {
+			t.Errorf("%q kept running after its deadline", input)
+		}
+	}
+}
+
+func TestSandboxBindings(t *testing.T) {
+	program := parser.New(lexer.New("let x = 1; let f = fn() { x + 1 }")).ParseProgram()
+	env := object.NewEnvironment()
+
+	EvalContext(context.Background(), program, env, Limits{MaxSteps: 100})
+
+	// Programs run with EvalContext bind their globals in env, usable once it returns
+	f, ok := env.Get("f")
+	if !ok {
+		t.Fatalf("f not bound in env")
+	}
+
+	testIntegerObject(t, ApplyFunction(f, nil, env), 2)
+}
+
+func TestOutput(t *testing.T) {
+	tests := []struct{
+		input  string
+		stdout string
+		stderr string
+	}{
+		{`puts(1, "a")`, "1\n\"a\"\n", ""},
+		{`print(1, "a"); print(2)`, "1 \"a\"2", ""},
+		{`eprint("oops")`, "", "\"oops\""},
+		{`let f = fn(x) { puts(x) }; f(1); [2].puts()`, "1\n[2]\n", ""},
+		{`let f = fn() { print(3) }; wait(spawn f())`, "3", ""},
+	}
+
+	for _, tc := range tests {
+		engines := map[string]func(program *ast.Program, env *object.Environment) object.Object{
+			"eval": func(program *ast.Program, env *object.Environment) object.Object { return Eval(program, env) },
+		}
+		for name, engine := range Engines {
+			engines[name] = engine
+		}
+
+		for name, engine := range engines {
+			var stdout, stderr strings.Builder
+			env := object.NewEnvironment()
+			env.SetRuntime(&object.Runtime{Stdout: &stdout, Stderr: &stderr})
+
+			program := parser.New(lexer.New(tc.input)).ParseProgram()
+			Resolve(program, env)
+			engine(program, env)
+
+			if stdout.String() != tc.stdout || stderr.String() != tc.stderr {
+				t.Errorf("wrong output of %q on %s. want=%q/%q, got=%q/%q", tc.input, name, tc.stdout, tc.stderr, stdout.String(), stderr.String())
+			}
+		}
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"-9223372036854775808", "-9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; -min", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; min / -1", "9223372036854775808"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"99999999999999999999", "99999999999999999999"},
+		{"let f = fn(n) { if (n < 2) { 1 } else { n * f(n - 1) } }; f(30)", "265252859812191058636308480000000"},
+		{"(9223372036854775807 + 1) - 1", "9223372036854775807"},
+		{"type(9223372036854775807 + 1)", `"INTEGER"`},
+		{"(9223372036854775807 + 1) / 2", "4611686018427387904"},
+		{"-(9223372036854775807 + 10)", "-9223372036854775817"},
+		{"99999999999999999999 / 0", "ERROR division by zero: it is impossible to divide by zero"},
+		{"99999999999999999999 > 1", "true"},
+		{"-99999999999999999999 < -9223372036854775807", "true"},
+		{"99999999999999999999 >= 99999999999999999999", "true"},
+		{"99999999999999999999 == 99999999999999999998 + 1", "true"},
+		{"99999999999999999999 != 1", "true"},
+		{"99999999999999999999 in 0..10", "false"},
+		{"[1, 2][99999999999999999999]", "nil"},
+		{"{99999999999999999999: 1}[99999999999999999998 + 1]", "1"},
+		{"sort([99999999999999999999, -99999999999999999999, 0, 5])", "[-99999999999999999999, 0, 5, 99999999999999999999]"},
+		{`"ab" * -99999999999999999999`, `""`},
+		{`"ab" * 99999999999999999999`, "ERROR string repetition count out of range: 99999999999999999999"},
+		{"0..99999999999999999999", "ERROR range bound out of range: 99999999999999999999"},
+		{"chan(99999999999999999999)", "ERROR channel size out of range: 99999999999999999999"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStrictIntegers(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 - 1", "9223372036854775806"},
+		{"-9223372036854775807 - 1", "-9223372036854775808"},
+		{"9223372036854775807 + 1", "1:21: OverflowError: integer overflow: 9223372036854775808 does not fit in 64 bits"},
+		{"let min = -9223372036854775807 - 1;\n-min", "2:1: OverflowError: integer overflow: 9223372036854775808 does not fit in 64 bits"},
+		{"4294967296 * 4294967296", "1:12: OverflowError: integer overflow: 18446744073709551616 does not fit in 64 bits"},
+		{"99999999999999999999", "1:1: OverflowError: integer overflow: 99999999999999999999 does not fit in 64 bits"},
+		{"try { 9223372036854775807 * 2 } catch (e) { e.code }", `"integer_overflow"`},
+	}
+
+	for _, tc := range tests {
+		engines := map[string]func(program *ast.Program, env *object.Environment) object.Object{
+			"eval": func(program *ast.Program, env *object.Environment) object.Object { return Eval(program, env) },
+		}
+		for name, engine := range Engines {
+			engines[name] = engine
+		}
+
+		for name, engine := range engines {
+			env := object.NewEnvironment()
+			env.SetStrictIntegers(true)
+
+			program := parser.New(lexer.New(tc.input)).ParseProgram()
+			Resolve(program, env)
+
+			result := engine(program, env)
+			got := result.Inspect()
+			if err, ok := result.(*object.Error); ok {
+				got = fmt.Sprintf("%s: %s: %s", err.Position(), err.Kind, err.Message)
+			}
+
+			if got != tc.expected {
+				t.Errorf("wrong result of %q on %s. want=%s, got=%s", tc.input, name, tc.expected, got)
+			}
+		}
+	}
+}
+
+func TestTraces(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"1 + true", ""},
+		{"let inner = fn(x) { x / 0 };\nlet outer = fn(x) { inner(x) + 1 };\nouter(1)", "Traceback (most recent call last):\n  at 3:6, in <program>\n  at 2:26, in outer\n  at 1:23, in inner\n"},
+		{"fn() { 1 + true }()", "Traceback (most recent call last):\n  at 1:18, in <program>\n  at 1:10, in <anonymous>\n"},
+		{"struct P { fn get(self) { self.missing } }\nP().get()", "Traceback (most recent call last):\n  at 2:8, in <program>\n  at 1:31, in P.get\n"},
+		{"let g = fn() { 1 + true }; let f = fn() { g() }; f()", "Traceback (most recent call last):\n  at 1:51, in <program>\n  at 1:18, in g\n"},
+		{"let f = fn(n) { if (n == 0) { throw 1 } else { 1 + f(n - 1) } }; f(1)", "Traceback (most recent call last):\n  at 1:67, in <program>\n  at 1:53, in f\n  at 1:31, in f\n"},
+		{"let f = fn() { 1 + true }; wait(spawn f())", "Traceback (most recent call last):\n  at 1:40, in <program>\n  at 1:18, in f\n"},
+		{"let g = fn() { 1 + true };\nlet h = fn(f) { try { f() } catch (e) { throw e } };\nlet first = try { h(g) } catch (e) { e };\nlet second = try { h(fn() { throw first }) } catch (e) { e };\nh(fn() { throw second })", "Traceback (most recent call last):\n  at 5:2, in <program>\n  at 2:41, in h\n"},
+	}
+
+	for _, tc := range tests {
+		err, ok := testEval(tc.input).(*object.Error)
+		if !ok {
+			t.Errorf("expected an error for %q", tc.input)
+			continue
+		}
+
+		if err.Traceback() != tc.expected {
+			t.Errorf("wrong traceback for %q. want=%q, got=%q", tc.input, tc.expected, err.Traceback())
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"1 + true", `["TypeError", "type_mismatch", [1, true]]`},
+		{`"a" - "b"`, `["TypeError", "unknown_operator", ["a", "b"]]`},
+		{"-true", `["TypeError", "unknown_operator", [true]]`},
+		{"1(2)", `["TypeError", "not_callable", [1]]`},
+		{"len(1)", `["TypeError", "invalid_argument", []]`},
+		{"let f = fn(x) { x }; f()", `["ArityError", "wrong_arguments", []]`},
+		{"len()", `["ArityError", "wrong_arguments", []]`},
+		{"7 / 0", `["ZeroDivision", "division_by_zero", [7, 0]]`},
+		{"1[0]", `["IndexError", "unsupported_index", [1, 0]]`},
+		{`"a" < 1`, `["TypeError", "type_mismatch", ["a", 1]]`},
+		{"[1] < [true]", `["TypeError", "unknown_operator", [[1], [true]]]`},
+		{`sort([1, "a"])`, `["TypeError", "not_comparable", ["a", 1]]`},
+		{"{}[{}]", `["KeyError", "unhashable_key", [{}]]`},
+		{`{[1, {"a": 2}]: 1}`, `["KeyError", "unhashable_key", [[1, {"a": 2}]]]`},
+		{"struct P { x } P(1).y", `["KeyError", "unknown_member", [P{x: 1}]]`},
+		{"for (x in 1) { x }", `["TypeError", "not_iterable", [1]]`},
+		{"let c = chan(); close(c); close(c)", `["RuntimeError", "closed_channel", []]`},
+		{`throw "boom"`, `["UserError", "thrown", []]`},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval("try { " + tc.input + " } catch (e) { [e.kind, e.code, e.values] }")
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("wrong error for %q. want=%s, got=%s", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+
+	// Names are reported before running, with the kind they'd fail with
+	if err, ok := testEval("missing").(*object.Error); !ok || err.Kind != "NameError" || err.Code != "identifier_not_found" {
+		t.Errorf("wrong error for an undefined name. got=%s", describe(testEval("missing")))
+	}
+}
+
+/*
+	Other engines running dx programs, registered by engines_test.go. Every
+	program evaluated by the tests runs on each of them too, and they must
+	agree with the evaluator on its result.
+*/
+var Engines = map[string]func(program *ast.Program, env *object.Environment) object.Object{}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
 
-	return Eval(program, env)
+	if errors := Resolve(program, env); len(errors) != 0 {
+		return &object.Error{Message: errors[0].Message, Kind: "NameError", Code: "identifier_not_found", Line: errors[0].Line, Column: errors[0].Column}
+	}
+
+	evaluated := Eval(program, env)
+
+	for name, engine := range Engines {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		env := object.NewEnvironment()
+		Resolve(program, env)
+
+		result := engine(program, env)
+
+		if !sameResult(evaluated, result) {
+			return newError("engine %s disagrees on %q. want=%s, got=%s", name, input, describe(evaluated), describe(result))
+		}
+	}
+
+	return evaluated
+}
+
+func sameResult(want, got object.Object) bool {
+	if want == nil || got == nil { return want == got }
+	if want.Type() != got.Type() { return false }
+
+	switch want := want.(type) {
+	case *object.Error:
+		got := got.(*object.Error)
+		return want.Message == got.Message && want.Kind == got.Kind && want.Code == got.Code && want.Line == got.Line && want.Column == got.Column &&
+			reflect.DeepEqual(want.Trace, got.Trace)
+	case *object.Array:
+		got := got.(*object.Array)
+		if len(want.Elements) != len(got.Elements) { return false }
+
+		for i := range want.Elements {
+			if !sameResult(want.Elements[i], got.Elements[i]) { return false }
+		}
+		return true
+	case *object.Hash:
+		got := got.(*object.Hash)
+		if want.Len() != got.Len() { return false }
+
+		for i, pair := range want.Pairs() {
+			other := got.Pairs()[i]
+			if !sameResult(pair.Key, other.Key) || !sameResult(pair.Value, other.Value) { return false }
+		}
+		return true
+	default:
+		return want.Inspect() == got.Inspect()
+	}
+}
+
+func describe(obj object.Object) string {
+	switch obj := obj.(type) {
+	case nil:
+		return "<nil>"
+	case *object.Error:
+		return fmt.Sprintf("%s at %s", obj.Message, obj.Position())
+	default:
+		return obj.Inspect()
+	}
 }
 
 func TestFunctionApplication(t *testing.T) {
@@ -534,6 +1602,33 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+/*
+	Checks evaluated against a table's expectation: integers and booleans by
+	value, strings against a string's value, an error's message or else the
+	object's Inspect, and nil against NIL.
+*/
+func testExpected(t *testing.T, evaluated object.Object, expected interface{}) {
+	t.Helper()
+
+	switch expected := expected.(type) {
+	case int:
+		testIntegerObject(t, evaluated, int64(expected))
+	case bool:
+		testBooleanObject(t, evaluated, expected)
+	case string:
+		switch obj := evaluated.(type) {
+		case *object.String:
+			if obj.Value != expected { t.Errorf("wrong string. want=%q, got=%q", expected, obj.Value) }
+		case *object.Error:
+			if obj.Message != expected { t.Errorf("wrong error message. want=%q, got=%q", expected, obj.Message) }
+		default:
+			if obj.Inspect() != expected { t.Errorf("wrong object. want=%s, got=%s", expected, obj.Inspect()) }
+		}
+	default:
+		testNilObject(t, evaluated)
+	}
+}
+
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	result, ok := obj.(*object.Integer)
 	if !ok {
@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"dux/object"
+	"strings"
+)
+
+/*
+	Default methods of the builtin types, looked up by the receiver's type on
+	x.name(). Calls to a name missing from the table fall back to the builtin
+	function of the same name, with the receiver as its first argument, so
+	s.len() is len(s) and arr.push(x).tail() is tail(push(arr, x)). Like
+	builtins, they're added to or removed per root environment through its
+	registry (see Builtins).
+*/
+var methods = map[object.ObjectType]map[string]*object.Builtin{
+	object.STRING_OBJ: {
+		"upper": stringMethod(strings.ToUpper),
+		"lower": stringMethod(strings.ToLower),
+		"trim":  stringMethod(strings.TrimSpace),
+		"split": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args)-1, 1) }
+
+				sep, ok := args[1].(*object.String)
+				if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `split` must be STRING, got %s", args[1].Type()) }
+
+				parts := strings.Split(args[0].(*object.String).Value, sep.Value)
+				elements := make([]object.Object, len(parts))
+				for i, part := range parts {
+					elements[i] = &object.String{Value: part}
+				}
+
+				return &object.Array{Elements: elements}
+			},
+		},
+		"starts_with": stringPredicate("starts_with", strings.HasPrefix),
+		"ends_with":   stringPredicate("ends_with", strings.HasSuffix),
+	},
+}
+
+/*
+	Looks member up among the methods of receiver's type, then among the
+	builtins of env, and binds it to receiver.
+*/
+func lookupMethod(receiver object.Object, member string, env *object.Environment) (object.Object, bool) {
+	var method *object.Builtin
+	var ok bool
+
+	if registry := env.Builtins(); registry != nil {
+		method, ok = registry.Method(receiver.Type(), member)
+	} else {
+		method, ok = methods[receiver.Type()][member]
+	}
+
+	if !ok {
+		method, ok = lookupBuiltin(member, env)
+	}
+
+	if !ok { return nil, false }
+
+	return &object.BoundMethod{Receiver: receiver, Method: method}, true
+}
+
+func stringMethod(fn func(string) string) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args)-1, 0) }
+
+			return &object.String{Value: fn(args[0].(*object.String).Value)}
+		},
+	}
+}
+
+func stringPredicate(name string, fn func(string, string) bool) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 { return newKindError("ArityError", "wrong_arguments", nil, "wrong number of arguments. got=%d, want=%d", len(args)-1, 1) }
+
+			arg, ok := args[1].(*object.String)
+			if !ok { return newKindError("TypeError", "invalid_argument", nil, "argument to `%s` must be STRING, got %s", name, args[1].Type()) }
+
+			return nativeBoolToBooleanObject(fn(args[0].(*object.String).Value, arg.Value))
+		},
+	}
+}
@@ -0,0 +1,174 @@
+package evaluator
+
+import (
+	"context"
+	"dux/ast"
+	"dux/object"
+	"math"
+)
+
+/*
+	Limits bound the evaluation of a program run with EvalContext. A zero
+	limit means no limit.
+*/
+type Limits struct {
+	MaxSteps  int64 // Evaluation steps, about one per node evaluated
+	MaxDepth  int64 // Calls in progress at once
+	MaxMemory int64 // Bytes allocated over the run, approximately
+}
+
+/*
+	Evaluates node in env within limits, until ctx is done. Hitting a limit or
+	ctx being done makes the program fail with an error of the LimitError
+	kind, which try can't catch.
+
+	The program runs in its own goroutine, so that a deadline is kept even while
+	it's blocked (i.e. receiving from a channel nothing sends to). Such a program
+	is left running, but its limits stay stopped: the next step it takes fails.
+	Functions run with the limits of their caller, those bound in env included.
+*/
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment, limits Limits) object.Object {
+	sandbox := object.NewSandbox(ctx, limits.MaxSteps, limits.MaxDepth, limits.MaxMemory)
+	sandboxed := object.NewSandboxedEnvironment(env, sandbox)
+
+	done := make(chan object.Object, 1)
+	go func() { done <- Eval(node, sandboxed) }()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return sandbox.Cancel()
+	}
+}
+
+// Approximate sizes, in bytes, of what programs allocate.
+const (
+	elementSize = 16 // An element of an array or channel buffer
+	pairSize    = 64 // A pair of a hash
+)
+
+// Charges bytes against the allocation budget of env's sandbox, if any.
+func allocate(env *object.Environment, bytes int64) object.Object {
+	sandbox := env.Sandbox()
+	if sandbox == nil || bytes <= 0 { return nil }
+
+	if err := sandbox.Allocate(bytes); err != nil { return err }
+
+	return nil
+}
+
+// Returns the bytes the operator allocates for its result, ahead of running it.
+func infixAllocation(operator string, left, right object.Object) int64 {
+	leftStr, leftIsStr := left.(*object.String)
+	rightStr, rightIsStr := right.(*object.String)
+
+	if allocated := bigIntegerAllocation(operator, left, right); allocated > 0 { return allocated }
+
+	switch {
+	case operator == "+" && leftIsStr && rightIsStr:
+		return int64(len(leftStr.Value) + len(rightStr.Value))
+	case operator == "*" && leftIsStr:
+		if times, ok := right.(*object.Integer); ok { return multiply(len(leftStr.Value), times.Value) }
+	case operator == "*" && rightIsStr:
+		if times, ok := left.(*object.Integer); ok { return multiply(len(rightStr.Value), times.Value) }
+	}
+
+	return 0
+}
+
+/*
+	Returns the bytes of the result of arithmetic on big integers, which grows
+	with its operands: the sum of their sizes for a product, the largest of
+	them otherwise.
+*/
+func bigIntegerAllocation(operator string, left, right object.Object) int64 {
+	_, leftIsBig := left.(*object.BigInteger)
+	_, rightIsBig := right.(*object.BigInteger)
+	if !leftIsBig && !rightIsBig { return 0 }
+
+	leftVal, lok := object.BigValue(left)
+	rightVal, rok := object.BigValue(right)
+	if !lok || !rok { return 0 }
+
+	leftSize, rightSize := int64(leftVal.BitLen()/8+1), int64(rightVal.BitLen()/8+1)
+
+	switch operator {
+	case "*":
+		return leftSize + rightSize
+	case "+", "-", "/":
+		return max(leftSize, rightSize)
+	}
+
+	return 0
+}
+
+/*
+	Returns the bytes calling function with args allocates, ahead of running
+	it, for builtins and builtin methods telling (see object.Builtin).
+*/
+func callAllocation(function object.Object, args []object.Object) int64 {
+	if bound, ok := function.(*object.BoundMethod); ok {
+		builtin, ok := bound.Method.(*object.Builtin)
+		if !ok || builtin.Allocates == nil { return 0 }
+
+		return builtin.Allocates(append([]object.Object{bound.Receiver}, args...))
+	}
+
+	builtin, ok := function.(*object.Builtin)
+	if !ok || builtin.Allocates == nil { return 0 }
+
+	return builtin.Allocates(args)
+}
+
+// Allocations of the builtins copying an array, with room for one more element.
+func copyAllocation(args []object.Object) int64 {
+	if len(args) == 0 { return 0 }
+
+	if array, ok := args[0].(*object.Array); ok { return multiply(len(array.Elements)+1, elementSize) }
+
+	return 0
+}
+
+// Allocations of the builtins collecting an iterable into an array.
+func collectAllocation(args []object.Object) int64 {
+	if len(args) == 0 { return 0 }
+
+	return iterableAllocation(args[0])
+}
+
+// Allocations of the builtins buffering as many elements as their argument.
+func bufferAllocation(args []object.Object) int64 {
+	if len(args) == 0 { return 0 }
+
+	if size, ok := args[0].(*object.Integer); ok { return multiply(elementSize, size.Value) }
+
+	return 0
+}
+
+/*
+	Returns the bytes collecting obj into an array allocates, for iterables
+	producing their elements without evaluating anything.
+*/
+func iterableAllocation(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Array:
+		return multiply(len(obj.Elements), elementSize)
+	case *object.Range:
+		return multiply(elementSize, obj.Len())
+	case *object.String:
+		return multiply(len(obj.Value), elementSize)
+	case *object.Hash:
+		return multiply(obj.Len(), pairSize)
+	}
+
+	return 0
+}
+
+// Multiplies a size by a count, saturating rather than overflowing.
+func multiply(size int, count int64) int64 {
+	if size <= 0 || count <= 0 { return 0 }
+	if count > math.MaxInt64/int64(size) { return math.MaxInt64 }
+
+	return int64(size) * count
+}
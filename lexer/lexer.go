@@ -7,6 +7,8 @@ type Lexer struct {
 	position int // Current position in input, current char
 	readPosition int // Current reading position in input, after current char
 	char byte // Current character under analysis
+	line int // Line of the current character, starting at 1
+	column int // Column of the current character, starting at 1
 }
 
 /*
@@ -21,8 +23,17 @@ type Lexer struct {
 	is setted as current *Lex.readPosition, and *Lex.readPosition is incremented by one.
 
 	*Lex.char points to 0 if it reach the end of file.
+
+	It also keeps *Lex.line and *Lex.column pointing to the current char, so
+	tokens can be tagged with the position they were read from.
 */
 func (lex *Lexer) readChar() {
+	if lex.char == '\n' {
+		lex.line++
+		lex.column = 0
+	}
+	lex.column++
+
 	if lex.readPosition >= len(lex.input) {
 		lex.char = 0
 	} else {
@@ -87,6 +98,19 @@ func (lex *Lexer) peekCharAhead() byte {
 	return lex.input[lex.readPosition]
 }
 
+/*
+	Like *Lexer.peekCharAhead, but looks n characters ahead of the current char
+	instead of only one. peekCharAt(1) is the same as peekCharAhead().
+*/
+func (lex *Lexer) peekCharAt(n int) byte {
+	position := lex.position + n
+	if position >= len(lex.input) {
+		return 0
+	}
+
+	return lex.input[position]
+}
+
 /*
 	Moves through source code, returning the first found valid Token. saves the current
 	reading position to future readings; returns a EOF Token when hits the end of file
@@ -96,6 +120,8 @@ func (lex *Lexer) NextToken() token.Token {
 	var tok token.Token
 	lex.eatGhostCharacters()
 
+	line, column := lex.line, lex.column
+
 	switch lex.char {
 	case '=':
 		if lex.peekCharAhead() == '=' {
@@ -103,6 +129,11 @@ func (lex *Lexer) NextToken() token.Token {
 			lex.readChar();
 
 			tok = token.Token{Type: token.EQUAL, Literal: string(char) + string(lex.char)}
+		} else if lex.peekCharAhead() == '>' {
+			char := lex.char
+			lex.readChar()
+
+			tok = token.Token{Type: token.FATARROW, Literal: string(char) + string(lex.char)}
 		} else {
 			tok = newToken(token.ASSIGN, lex.char)
 		}
@@ -130,15 +161,36 @@ func (lex *Lexer) NextToken() token.Token {
 			tok = newToken(token.EXCLAMATION, lex.char)
 		}
 	case '-':
-		tok = newToken(token.MINUS, lex.char)
+		if lex.peekCharAhead() == '>' {
+			char := lex.char
+			lex.readChar()
+
+			tok = token.Token{Type: token.ARROW, Literal: string(char) + string(lex.char)}
+		} else {
+			tok = newToken(token.MINUS, lex.char)
+		}
 	case '/':
 		tok = newToken(token.RBAR, lex.char)
 	case '*':
 		tok = newToken(token.STAR, lex.char)
 	case '<':
-		tok = newToken(token.STHAN, lex.char)
+		if lex.peekCharAhead() == '=' {
+			char := lex.char
+			lex.readChar()
+
+			tok = token.Token{Type: token.STHANEQ, Literal: string(char) + string(lex.char)}
+		} else {
+			tok = newToken(token.STHAN, lex.char)
+		}
 	case '>':
-		tok = newToken(token.GTHAN, lex.char)
+		if lex.peekCharAhead() == '=' {
+			char := lex.char
+			lex.readChar()
+
+			tok = token.Token{Type: token.GTHANEQ, Literal: string(char) + string(lex.char)}
+		} else {
+			tok = newToken(token.GTHAN, lex.char)
+		}
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = lex.readString()
@@ -151,20 +203,44 @@ func (lex *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, lex.char)
 	case ':':
 		tok = newToken(token.COLON, lex.char)
+	case '?':
+		// '?' is only part of an identifier after its first letter (i.e. empty?)
+		tok = newToken(token.QUESTION, lex.char)
+	case '.':
+		if lex.peekCharAhead() == '.' && lex.peekCharAt(2) == '.' {
+			lex.readChar()
+			lex.readChar()
+
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else if lex.peekCharAhead() == '.' && lex.peekCharAt(2) == '=' {
+			lex.readChar()
+			lex.readChar()
+
+			tok = token.Token{Type: token.DOTDOTEQ, Literal: "..="}
+		} else if lex.peekCharAhead() == '.' {
+			lex.readChar()
+
+			tok = token.Token{Type: token.DOTDOT, Literal: ".."}
+		} else {
+			tok = newToken(token.DOT, lex.char)
+		}
 	default:
 		if isLetter(lex.char) {
 			tok.Literal = lex.readIdentifier()
 			tok.Type = token.LookupType(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isDigit(lex.char) {
 			tok.Literal = lex.readNumber()
 			tok.Type = token.INT
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, lex.char)
 		}
 	}
 
+	tok.Line, tok.Column = line, column
 	lex.readChar()
 
 	return tok
@@ -182,7 +258,7 @@ func (l *Lexer) readString() string {
 }
 
 func New(input string) *Lexer {
-	lex := &Lexer{input: input}
+	lex := &Lexer{input: input, line: 1}
 	lex.readChar()
 
 	return lex
@@ -31,6 +31,16 @@ func TestNextToken(t *testing.T) {
 		"foo bar"
 		[1, 2];
 		{"foo": "bar"}
+		[...xs]
+		for (i in 0..=10) {}
+		1..n
+		acct.balance
+		_ => 1
+		f(x)? empty?
+		fn(x: int) -> int
+		yield x
+		spawn select
+		a <= b >= c
 	`
 
 	tests := []struct {
@@ -123,6 +133,52 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "xs"},
+		{token.RBRACKET, "]"},
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "i"},
+		{token.IN, "in"},
+		{token.INT, "0"},
+		{token.DOTDOTEQ, "..="},
+		{token.INT, "10"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.INT, "1"},
+		{token.DOTDOT, ".."},
+		{token.IDENT, "n"},
+		{token.IDENT, "acct"},
+		{token.DOT, "."},
+		{token.IDENT, "balance"},
+		{token.IDENT, "_"},
+		{token.FATARROW, "=>"},
+		{token.INT, "1"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "empty?"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.YIELD, "yield"},
+		{token.IDENT, "x"},
+		{token.SPAWN, "spawn"},
+		{token.SELECT, "select"},
+		{token.IDENT, "a"},
+		{token.STHANEQ, "<="},
+		{token.IDENT, "b"},
+		{token.GTHANEQ, ">="},
+		{token.IDENT, "c"},
 		{token.EOF, ""},
 	}
 
@@ -136,3 +192,38 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"ab\"\n\n  try"
+
+	tests := []struct{
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"ab", 2, 7},
+		{"try", 4, 3},
+		{"", 4, 6},
+	}
+
+	lex := New(input)
+
+	for index, test := range tests {
+		tok := lex.NextToken()
+
+		if tok.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got=%q", index, test.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != test.expectedLine || tok.Column != test.expectedColumn {
+			t.Errorf("tests[%d] - wrong position for %q. expected=%d:%d, got=%d:%d", index, tok.Literal, test.expectedLine, test.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
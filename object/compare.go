@@ -0,0 +1,161 @@
+package object
+
+import "strings"
+
+/*
+	Equatable objects compare by value rather than by identity: integers (small
+	or big), strings and booleans by their value, arrays element-wise, hashes
+	by their keys and the values under them (in whatever order they were set),
+	tagged values by tag and payload, and struct instances by struct and fields.
+*/
+type Equatable interface {
+	Object
+	Equal(other Object) bool
+}
+
+/*
+	Comparable objects are ordered against other objects of their type:
+	integers numerically (small and big alike), strings lexicographically
+	(byte-wise) and arrays element by element. Compare returns -1, 0 or 1, and false if other can't be
+	ordered against the receiver.
+*/
+type Comparable interface {
+	Object
+	Compare(other Object) (int, bool)
+}
+
+// Reports whether left and right are equal, by value for Equatable objects and by identity otherwise.
+func Equal(left, right Object) bool {
+	if equatable, ok := left.(Equatable); ok { return equatable.Equal(right) }
+
+	return left == right
+}
+
+// Orders left against right, reporting false if they can't be ordered (see Comparable).
+func Compare(left, right Object) (int, bool) {
+	comparable, ok := left.(Comparable)
+	if !ok { return 0, false }
+
+	return comparable.Compare(right)
+}
+
+func (i *Integer) Equal(other Object) bool {
+	o, ok := other.(*Integer)
+	return ok && i.Value == o.Value
+}
+
+func (i *Integer) Compare(other Object) (int, bool) {
+	// Big integers are out of the range of small ones, on the side of their sign
+	if o, ok := other.(*BigInteger); ok { return -o.Value.Sign(), true }
+
+	o, ok := other.(*Integer)
+	if !ok { return 0, false }
+
+	switch {
+	case i.Value < o.Value:
+		return -1, true
+	case i.Value > o.Value:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func (bi *BigInteger) Equal(other Object) bool {
+	o, ok := other.(*BigInteger)
+	return ok && bi.Value.Cmp(o.Value) == 0
+}
+
+func (bi *BigInteger) Compare(other Object) (int, bool) {
+	switch o := other.(type) {
+	case *BigInteger:
+		return bi.Value.Cmp(o.Value), true
+	case *Integer:
+		return bi.Value.Sign(), true
+	default:
+		return 0, false
+	}
+}
+
+func (s *String) Equal(other Object) bool {
+	o, ok := other.(*String)
+	return ok && s.Value == o.Value
+}
+
+func (s *String) Compare(other Object) (int, bool) {
+	o, ok := other.(*String)
+	if !ok { return 0, false }
+
+	return strings.Compare(s.Value, o.Value), true
+}
+
+func (b *Boolean) Equal(other Object) bool {
+	o, ok := other.(*Boolean)
+	return ok && b.Value == o.Value
+}
+
+func (a *Array) Equal(other Object) bool {
+	o, ok := other.(*Array)
+	if !ok || len(a.Elements) != len(o.Elements) { return false }
+
+	for i, element := range a.Elements {
+		if !Equal(element, o.Elements[i]) { return false }
+	}
+
+	return true
+}
+
+// Arrays order by their first unequal element, then by length: [1, 2] < [1, 3] and [1] < [1, 2].
+func (a *Array) Compare(other Object) (int, bool) {
+	o, ok := other.(*Array)
+	if !ok { return 0, false }
+
+	for i := 0; i < len(a.Elements) && i < len(o.Elements); i++ {
+		order, ok := Compare(a.Elements[i], o.Elements[i])
+		if !ok { return 0, false }
+		if order != 0 { return order, true }
+	}
+
+	switch {
+	case len(a.Elements) < len(o.Elements):
+		return -1, true
+	case len(a.Elements) > len(o.Elements):
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func (h *Hash) Equal(other Object) bool {
+	o, ok := other.(*Hash)
+	if !ok || h.Len() != o.Len() { return false }
+
+	for _, pair := range h.pairs {
+		value, ok := o.Get(pair.Key)
+		if !ok || !Equal(pair.Value, value) { return false }
+	}
+
+	return true
+}
+
+func (ev *EnumValue) Equal(other Object) bool {
+	o, ok := other.(*EnumValue)
+	if !ok || ev.Variant != o.Variant || len(ev.Payload) != len(o.Payload) { return false }
+
+	for i, value := range ev.Payload {
+		if !Equal(value, o.Payload[i]) { return false }
+	}
+
+	return true
+}
+
+func (i *Instance) Equal(other Object) bool {
+	o, ok := other.(*Instance)
+	if !ok || i.Struct != o.Struct { return false }
+
+	for _, name := range i.Struct.Fields {
+		if !Equal(i.Fields[name], o.Fields[name]) { return false }
+	}
+
+	return true
+}
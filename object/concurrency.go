@@ -0,0 +1,82 @@
+package object
+
+import "fmt"
+
+/*
+	A Channel passes values between spawned tasks. Sends block while its buffer
+	is full and receives block until a value is available, or the channel is
+	closed and drained.
+*/
+type Channel struct {
+	Ch chan Object
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string { return fmt.Sprintf("chan(%d)", cap(c.Ch)) }
+
+// Sends value over the channel. Returns false if the channel is closed.
+func (c *Channel) Send(value Object) (sent bool) {
+	defer func() {
+		if recover() != nil { sent = false }
+	}()
+
+	c.Ch <- value
+
+	return true
+}
+
+// Returns false if the channel was already closed.
+func (c *Channel) Close() (closed bool) {
+	defer func() {
+		if recover() != nil { closed = false }
+	}()
+
+	close(c.Ch)
+
+	return true
+}
+
+// Iterating over a channel receives values until it's closed.
+func (c *Channel) Iterator() Iterator { return c }
+
+func (c *Channel) Next() (Object, bool) {
+	value, ok := <-c.Ch
+	return value, ok
+}
+
+// A Task is a function call running on a goroutine of its own.
+type Task struct {
+	done   chan struct{}
+	result Object
+}
+
+func NewTask(run func() Object) *Task {
+	t := &Task{done: make(chan struct{})}
+
+	go func() {
+		t.result = run()
+		close(t.done)
+	}()
+
+	return t
+}
+
+func (t *Task) Type() ObjectType { return TASK_OBJ }
+func (t *Task) Inspect() string { return "task" }
+
+/*
+	Blocks until the task is done and returns the value its call evaluated to.
+	Each waiter gets a copy of an error, since waiters unwind calls of their
+	own and add them to its trace.
+*/
+func (t *Task) Wait() Object {
+	<-t.done
+
+	if err, ok := t.result.(*Error); ok {
+		waited := *err
+		waited.Trace = append([]Frame(nil), err.Trace...)
+		return &waited
+	}
+
+	return t.result
+}
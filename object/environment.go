@@ -1,5 +1,10 @@
 package object
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 func NewEnvironment() *Environment {
 	hp := make(map[string]Object)
 	return &Environment{store: hp, outer: nil}
@@ -8,17 +13,134 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.sandbox.Store(outer.Sandbox())
+	env.builtins.Store(outer.Builtins())
+	env.runtime.Store(outer.Runtime())
+	env.strictIntegers.Store(outer.StrictIntegers())
+
+	return env
+}
+
+/*
+	Returns an environment enclosed by outer for the bindings the resolver put
+	in slots, with room for size of them. Names bound by name in it are given
+	a map on first use.
+*/
+func NewScope(outer *Environment, size int) *Environment {
+	env := &Environment{slots: make([]Object, size), outer: outer}
+	env.sandbox.Store(outer.Sandbox())
+	env.builtins.Store(outer.Builtins())
+	env.runtime.Store(outer.Runtime())
+	env.strictIntegers.Store(outer.StrictIntegers())
 
 	return env
 }
 
+/*
+	Returns the scope of a call to a function closing over outer, with room for
+	size bindings. Yield statements look for their generator up to the
+	nearest call frame only, so a closure escaping a generator's body doesn't
+	yield into it.
+*/
+func NewFrame(outer *Environment, size int) *Environment {
+	env := NewScope(outer, size)
+	env.frame = true
+
+	return env
+}
+
+/*
+	Returns an environment running a program in outer under sandbox: it binds
+	names in outer as if it were outer, but its sandbox is its own and stays
+	set however long the program runs, unlike one set on outer would.
+*/
+func NewSandboxedEnvironment(outer *Environment, sandbox *Sandbox) *Environment {
+	env := &Environment{outer: outer, forward: true}
+	env.sandbox.Store(sandbox)
+	env.builtins.Store(outer.Builtins())
+	env.runtime.Store(outer.Runtime())
+	env.strictIntegers.Store(outer.StrictIntegers())
+
+	return env
+}
+
+/*
+	An Environment is safe to share between spawned tasks: concurrent lookups
+	and bindings on it are serialized by its lock.
+*/
 type Environment struct {
+	mu    sync.RWMutex
 	store map[string]Object
+	slots []Object
 	outer *Environment
+
+	forward   bool           // Bindings go to outer (see NewSandboxedEnvironment)
+	frame     bool           // Set on the environment of a function call (see NewFrame)
+	generator *generatorState // Set on the environment of a generator body
+	sandbox   atomic.Pointer[Sandbox]
+	builtins  atomic.Pointer[Registry]
+	runtime   atomic.Pointer[Runtime]
+
+	strictIntegers atomic.Bool
+}
+
+/*
+	Returns the sandbox bounding evaluation in e, if any. Enclosed environments
+	take the sandbox of their outer environment when they're created, and
+	those of function calls the sandbox of the caller.
+*/
+func (e *Environment) Sandbox() *Sandbox {
+	if e == nil { return nil }
+
+	return e.sandbox.Load()
+}
+
+func (e *Environment) SetSandbox(sandbox *Sandbox) { e.sandbox.Store(sandbox) }
+
+/*
+	Returns the builtins programs running in e can call, nil for the default
+	ones. Enclosed environments take the builtins of their outer environment
+	when they're created, so they're meant to be set on a root environment
+	before anything runs in it.
+*/
+func (e *Environment) Builtins() *Registry {
+	if e == nil { return nil }
+
+	return e.builtins.Load()
+}
+
+func (e *Environment) SetBuiltins(builtins *Registry) { e.builtins.Store(builtins) }
+
+/*
+	Returns the runtime builtins called from e do I/O with, nil for the streams
+	of the process. Like builtins, it's meant to be set on a root environment.
+*/
+func (e *Environment) Runtime() *Runtime {
+	if e == nil { return nil }
+
+	return e.runtime.Load()
+}
+
+func (e *Environment) SetRuntime(runtime *Runtime) { e.runtime.Store(runtime) }
+
+/*
+	Reports whether integers overflowing 64 bits in e fail with an
+	OverflowError, rather than being promoted to a BigInteger. Like builtins,
+	it's meant to be set on a root environment.
+*/
+func (e *Environment) StrictIntegers() bool {
+	if e == nil { return false }
+
+	return e.strictIntegers.Load()
 }
 
+func (e *Environment) SetStrictIntegers(strict bool) { e.strictIntegers.Store(strict) }
+
 func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	obj, ok := e.store[name]
+	e.mu.RUnlock()
+
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
@@ -27,6 +149,76 @@ func (e *Environment) Get(name string) (Object, bool) {
 }
 
 func (e *Environment) Set(name string, obj Object) Object {
+	if e.forward { return e.outer.Set(name, obj) }
+
+	e.mu.Lock()
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = obj
+	e.mu.Unlock()
+
 	return obj
 }
+
+// Returns the environment depth levels out from e.
+func (e *Environment) Ancestor(depth int) *Environment {
+	env := e
+	for ; depth > 0; depth-- {
+		env = env.outer
+	}
+
+	return env
+}
+
+// Returns the object in slot of the environment depth levels out from e.
+func (e *Environment) GetSlot(depth, slot int) (Object, bool) {
+	env := e.Ancestor(depth)
+
+	env.mu.RLock()
+	var obj Object
+	if slot < len(env.slots) {
+		obj = env.slots[slot]
+	}
+	env.mu.RUnlock()
+
+	return obj, obj != nil
+}
+
+func (e *Environment) SetSlot(slot int, obj Object) Object {
+	if e.forward { return e.outer.SetSlot(slot, obj) }
+
+	e.mu.Lock()
+	if slot >= len(e.slots) {
+		e.slots = append(e.slots, make([]Object, slot+1-len(e.slots))...)
+	}
+	e.slots[slot] = obj
+	e.mu.Unlock()
+
+	return obj
+}
+
+// Reports whether e is inside the body of a generator.
+func (e *Environment) InGenerator() bool { return e.running() != nil }
+
+/*
+	Hands value over to the consumer of the generator whose body is running in
+	e, suspending the body until the next value is asked for. Returns false if
+	e isn't inside a generator, or the generator was dropped, in which case the
+	body must unwind.
+*/
+func (e *Environment) Yield(value Object) bool {
+	if state := e.running(); state != nil { return state.yield(value) }
+
+	return false
+}
+
+// Returns the generator whose body the call frame of e evaluates, if any.
+func (e *Environment) running() *generatorState {
+	for env := e; env != nil; env = env.outer {
+		if env.generator != nil { return env.generator }
+		if env.frame { return nil }
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package object
+
+import (
+	"runtime"
+	"sync"
+)
+
+/*
+	A Generator is the lazy iterator returned by calling a function that yields.
+	Its body runs on a goroutine of its own, taking turns with the consumer:
+	each call to Next resumes the body until it yields again, so the body's
+	environment and position stay suspended in between and values are only
+	computed when asked for.
+
+	A body failing with an *Error hands the error over as its last value.
+	Generators may be shared between spawned tasks, each value going to one of
+	them.
+*/
+type Generator struct {
+	state *generatorState
+}
+
+/*
+	The part of a generator shared with its body. It's kept apart from the
+	Generator so that a generator dropped before it's exhausted can still be
+	garbage collected, which stops its suspended body.
+*/
+type generatorState struct {
+	body    func() Object
+	yields  chan Object
+	resume  chan struct{}
+	stop    chan struct{}
+	mu      sync.Mutex // Held by the consumer while the body runs
+	started bool
+	done    bool
+}
+
+/*
+	Creates a generator running body, which evaluates the generator function in
+	env. Yield statements inside body find the generator through env.
+*/
+func NewGenerator(env *Environment, body func() Object) *Generator {
+	state := &generatorState{
+		body:   body,
+		yields: make(chan Object),
+		resume: make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	env.generator = state
+
+	g := &Generator{state: state}
+	runtime.SetFinalizer(g, func(g *Generator) { close(g.state.stop) })
+
+	return g
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string { return "generator" }
+
+func (g *Generator) Iterator() Iterator { return g }
+
+func (g *Generator) Next() (Object, bool) {
+	s := g.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done { return nil, false }
+
+	if !s.started {
+		s.started = true
+		go s.run()
+	} else {
+		s.resume <- struct{}{}
+	}
+
+	value, ok := <-s.yields
+	if !ok {
+		s.done = true
+		return nil, false
+	}
+
+	// A failed body doesn't resume after handing its error over
+	if _, failed := value.(*Error); failed {
+		s.done = true
+	}
+
+	return value, true
+}
+
+func (s *generatorState) run() {
+	defer close(s.yields)
+
+	if err, ok := s.body().(*Error); ok {
+		select {
+		case s.yields <- err:
+		case <-s.stop:
+		}
+	}
+}
+
+/*
+	Hands value over to the consumer and blocks until the next value is asked
+	for. Returns false if the generator was dropped instead, in which case the
+	body must unwind.
+*/
+func (s *generatorState) yield(value Object) bool {
+	select {
+	case s.yields <- value:
+	case <-s.stop:
+		return false
+	}
+
+	select {
+	case <-s.resume:
+		return true
+	case <-s.stop:
+		return false
+	}
+}
@@ -0,0 +1,101 @@
+package object
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+)
+
+/*
+	Returns the hash key of obj, reporting false if obj can't key a hash.
+	Besides Hashable objects, arrays and struct instances are composite keys
+	when all of their elements, or fields, can be keys themselves. Neither can
+	be changed once built, so the pairs they key stay where they were set.
+*/
+func HashKeyOf(obj Object) (HashKey, bool) {
+	switch obj := obj.(type) {
+	case Hashable:
+		return obj.HashKey(), true
+	case *Array:
+		hk := fnv.New64a()
+		for _, element := range obj.Elements {
+			key, ok := HashKeyOf(element)
+			if !ok { return HashKey{}, false }
+
+			writeHashKey(hk, key)
+		}
+
+		return HashKey{Type: ARRAY_OBJ, Value: hk.Sum64()}, true
+	case *Instance:
+		hk := fnv.New64a()
+		for _, name := range obj.Struct.Fields {
+			key, ok := HashKeyOf(obj.Fields[name])
+			if !ok { return HashKey{}, false }
+
+			writeHashKey(hk, key)
+		}
+
+		return HashKey{Type: obj.Type(), Value: hk.Sum64()}, true
+	default:
+		return HashKey{}, false
+	}
+}
+
+// Writes key to the hash of a composite key.
+func writeHashKey(w io.Writer, key HashKey) {
+	fmt.Fprintf(w, "%s:%d;", key.Type, key.Value)
+}
+
+// Returns an empty hash with room for size pairs.
+func NewHash(size int) *Hash {
+	return &Hash{pairs: make([]HashPair, 0, size), index: make(map[HashKey][]int, size)}
+}
+
+// Returns the value key is set to, if any. Keys that can't key a hash are in none.
+func (h *Hash) Get(key Object) (Object, bool) {
+	_, i, _ := h.lookup(key)
+	if i < 0 { return nil, false }
+
+	return h.pairs[i].Value, true
+}
+
+/*
+	Sets key to value, at the end of the hash unless key is in it already.
+	Reports false, leaving the hash as it is, if key can't key a hash.
+*/
+func (h *Hash) Set(key, value Object) bool {
+	hashed, i, ok := h.lookup(key)
+	if !ok { return false }
+
+	if i >= 0 {
+		h.pairs[i].Value = value
+		return true
+	}
+
+	if h.index == nil { h.index = map[HashKey][]int{} }
+
+	h.index[hashed] = append(h.index[hashed], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+
+	return true
+}
+
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// Returns the pairs of the hash in insertion order, which must not be modified.
+func (h *Hash) Pairs() []HashPair { return h.pairs }
+
+/*
+	Returns the hash key of key and its position in pairs, -1 if it's not in
+	the hash, reporting false if it can't key a hash.
+*/
+func (h *Hash) lookup(key Object) (HashKey, int, bool) {
+	hashed, ok := HashKeyOf(key)
+	if !ok { return hashed, -1, false }
+
+	for _, i := range h.index[hashed] {
+		if Equal(h.pairs[i].Key, key) { return hashed, i, true }
+	}
+
+	return hashed, -1, true
+}
@@ -0,0 +1,83 @@
+package object
+
+import (
+	"hash/fnv"
+	"math"
+	"math/big"
+)
+
+/*
+	A BigInteger is an integer too large for an Integer, which arithmetic on
+	integers promotes its results to rather than overflowing. It's an INTEGER
+	like any other: results that fit in 64 bits again are Integers, so that a
+	BigInteger always holds a value out of the range of int64 (see IntegerOf).
+	Its value is never changed once built.
+*/
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (bi *BigInteger) Type() ObjectType { return INTEGER_OBJ }
+func (bi *BigInteger) Inspect() string  { return bi.Value.String() }
+func (bi *BigInteger) HashKey() HashKey {
+	hk := fnv.New64a()
+	hk.Write([]byte{byte(bi.Value.Sign() + 1)})
+	hk.Write(bi.Value.Bytes())
+
+	return HashKey{Type: INTEGER_OBJ, Value: hk.Sum64()}
+}
+
+// Returns value as an Integer if it fits in 64 bits, or else as a BigInteger.
+func IntegerOf(value *big.Int) Object {
+	if value.IsInt64() { return &Integer{Value: value.Int64()} }
+
+	return &BigInteger{Value: value}
+}
+
+// Returns the value of an INTEGER, small or big, as a big.Int to be read only.
+func BigValue(obj Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value), true
+	case *BigInteger:
+		return obj.Value, true
+	default:
+		return nil, false
+	}
+}
+
+/*
+	The operations below do 64-bit integer arithmetic, reporting false when the
+	result overflows.
+*/
+
+func AddInt64(a, b int64) (int64, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) { return 0, false }
+
+	return sum, true
+}
+
+func SubInt64(a, b int64) (int64, bool) {
+	diff := a - b
+	if (b > 0 && diff > a) || (b < 0 && diff < a) { return 0, false }
+
+	return diff, true
+}
+
+func MulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 { return 0, true }
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) { return 0, false }
+
+	product := a * b
+	if product/b != a { return 0, false }
+
+	return product, true
+}
+
+// Division truncates toward zero; dividing by zero is left to the caller.
+func DivInt64(a, b int64) (int64, bool) {
+	if a == math.MinInt64 && b == -1 { return 0, false }
+
+	return a / b, true
+}
@@ -3,8 +3,10 @@ package object
 import (
 	"bytes"
 	"dux/ast"
+	"dux/token"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"strings"
 )
 
@@ -15,14 +17,38 @@ const (
 	INTEGER_OBJ      = "INTEGER"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	TAIL_CALL_OBJ    = "TAIL_CALL"
 	ERROR_OBJ        = "ERROR"
+	EXCEPTION_OBJ    = "EXCEPTION"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	RANGE_OBJ        = "RANGE"
+	STRUCT_OBJ       = "STRUCT"
+	BOUND_METHOD_OBJ = "BOUND_METHOD"
+	ENUM_OBJ         = "ENUM"
+	VARIANT_OBJ      = "ENUM_VARIANT"
+	GENERATOR_OBJ    = "GENERATOR"
+	CHANNEL_OBJ      = "CHANNEL"
+	TASK_OBJ         = "TASK"
 )
 
+var builtinTypes = map[ObjectType]bool{
+	NIL_OBJ: true, INTEGER_OBJ: true, BOOLEAN_OBJ: true, RETURN_VALUE_OBJ: true, TAIL_CALL_OBJ: true,
+	ERROR_OBJ: true, EXCEPTION_OBJ: true, FUNCTION_OBJ: true, STRING_OBJ: true, BUILTIN_OBJ: true,
+	ARRAY_OBJ: true, HASH_OBJ: true, RANGE_OBJ: true, STRUCT_OBJ: true, BOUND_METHOD_OBJ: true,
+	ENUM_OBJ: true, VARIANT_OBJ: true, GENERATOR_OBJ: true, CHANNEL_OBJ: true, TASK_OBJ: true,
+}
+
+/*
+	Reports whether name is the type of a built-in object. Instances and tagged
+	values report their struct or enum name as type, so one named like a
+	built-in type would pass for it; such names are rejected.
+*/
+func IsBuiltinType(name string) bool { return builtinTypes[ObjectType(name)] }
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
@@ -42,14 +68,62 @@ type ReturnValue struct {
 	Value Object
 }
 
+/*
+	A TailCall is a call left for the caller to make: functions hand it back
+	instead of recursing when it's the last thing they do, so the caller can
+	run it in a loop without growing the stack.
+*/
+type TailCall struct {
+	Function  Object
+	Arguments []Object
+	Token     token.Token // The call site, to position errors raised by the call
+}
+
+/*
+	An Error is a dx program failing. Kind is what went wrong, the same for all
+	errors of a class: TypeError, NameError, ArityError, ZeroDivision,
+	IndexError, KeyError and RuntimeError for errors raised by the interpreter,
+	UserError for thrown values and LimitError for sandbox limits. Code tells
+	errors of the same kind apart (i.e. type_mismatch and unknown_operator are
+	both TypeErrors), and Values are the values the error is about, if any.
+*/
 type Error struct {
 	Message string
+	Kind    string
+	Code    string
+	Values  []Object
+	Value   Object  // The thrown value, nil for runtime errors
+	Line    int     // Position of the expression that raised the error, 0 when unknown
+	Column  int
+	Trace   []Frame // Calls the error unwound, innermost first
+}
+
+/*
+	A Frame is a call of a dx function an error unwound: the function, and the
+	position of the call. Tail calls replace the frame of their caller, so the
+	function is the one that was running when the error was raised.
+*/
+type Frame struct {
+	Function string // Empty for anonymous functions
+	Line     int    // Position of the call, 0 until the call site is known
+	Column   int
+}
+
+/*
+	An Exception is an *Error intercepted by a catch clause. Unlike an *Error it
+	doesn't propagate, so it can be bound to a name and passed around; throwing
+	it again raises the original error.
+*/
+type Exception struct {
+	Error *Error
 }
 
 type Function struct {
-	Parameters []*ast.Identifier
-	Body       *ast.BlockStatement
-	Env        *Environment
+	Parameters  []*ast.Identifier
+	Body        *ast.BlockStatement
+	Env         *Environment
+	IsGenerator bool
+	Name        string // Name of the literal it was created from, empty for anonymous ones
 }
 
 type String struct {
@@ -58,8 +132,13 @@ type String struct {
 
 type BuiltinFunction func(args ...Object) Object
 
+// Builtins doing I/O are given the runtime of the environment they're called from.
+type RuntimeFunction func(runtime *Runtime, args ...Object) Object
+
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn        BuiltinFunction
+	RuntimeFn RuntimeFunction // Called instead of Fn when set
+	Allocates func(args []Object) int64 // Bytes a call allocates, charged to its sandbox ahead of it; nil for none
 }
 
 type Array struct {
@@ -71,21 +150,333 @@ type HashPair struct {
 	Value Object
 }
 
+/*
+	A Hash keeps its pairs in the order their keys were first set, which is the
+	order it's inspected and iterated in, while looking keys up in constant
+	time. Setting a key already present replaces its value in place.
+
+	Keys are found by their hash key, then compared with Equal, so that keys
+	whose hash keys collide are kept apart.
+*/
 type Hash struct {
-	Pairs map[uint64]HashPair
+	pairs []HashPair
+	index map[HashKey][]int // Positions in pairs of the keys hashing to every hash key
+}
+
+/*
+	A HashKey is what a hash key is hashed to. It's made of the type of the
+	key, so that keys of different types never collide (i.e. 1 and true).
+*/
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
 }
 
 type Hashable interface {
-	HashKey() uint64
+	Object
+	HashKey() HashKey
+}
+
+/*
+	Accessible objects look their members up themselves (i.e. Go values bound
+	by a host program), reporting whether they have one named name.
+*/
+type Accessible interface {
+	Object
+	Member(name string) (Object, bool)
+}
+
+/*
+	A Struct is a user-defined record type. Calling it builds an *Instance whose
+	fields are given positionally, in the same order they were declared.
+
+	Inspector, when set, renders instances in place of the default
+	Name{field: value} output; the evaluator sets it for structs declaring an
+	inspect method.
+*/
+type Struct struct {
+	Name      string
+	Fields    []string
+	Methods   map[string]*Function
+	Inspector func(instance *Instance) string
+}
+
+type Instance struct {
+	Struct *Struct
+	Fields map[string]Object
+}
+
+/*
+	A BoundMethod is a method taken from its receiver (i.e. acct.deposit); the
+	receiver is passed as the first argument once the method is called.
+*/
+type BoundMethod struct {
+	Receiver Object
+	Method   Object
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
+func (s *Struct) Inspect() string {
+	return "struct " + s.Name + " { " + strings.Join(s.Fields, ", ") + " }"
+}
+
+// Instances report their struct name as type, i.e. type(acct) is "Account".
+func (i *Instance) Type() ObjectType { return ObjectType(i.Struct.Name) }
+func (i *Instance) Inspect() string {
+	if i.Struct.Inspector != nil {
+		return i.Struct.Inspector(i)
+	}
+
+	var out strings.Builder
+
+	fields := []string{}
+	for _, name := range i.Struct.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", name, i.Fields[name].Inspect()))
+	}
+
+	out.WriteString(i.Struct.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (bm *BoundMethod) Type() ObjectType { return BOUND_METHOD_OBJ }
+func (bm *BoundMethod) Inspect() string { return "bound method" }
+
+/*
+	An Enum is a closed set of tagged variants. Variants declaring fields are
+	constructors, called to build a tagged value carrying a payload (i.e.
+	Status.Settled(10)); plain variants are tagged values on their own.
+*/
+type Enum struct {
+	Name     string
+	Variants []*EnumVariant
+}
+
+type EnumVariant struct {
+	Enum   *Enum
+	Name   string
+	Fields []string
+}
+
+type EnumValue struct {
+	Variant *EnumVariant
+	Payload []Object
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	variants := []string{}
+	for _, variant := range e.Variants {
+		if len(variant.Fields) == 0 {
+			variants = append(variants, variant.Name)
+		} else {
+			variants = append(variants, variant.Name+"("+strings.Join(variant.Fields, ", ")+")")
+		}
+	}
+
+	return "enum " + e.Name + " { " + strings.Join(variants, ", ") + " }"
+}
+
+func (e *Enum) Variant(name string) (*EnumVariant, bool) {
+	for _, variant := range e.Variants {
+		if variant.Name == name { return variant, true }
+	}
+
+	return nil, false
+}
+
+func (ev *EnumVariant) Type() ObjectType { return VARIANT_OBJ }
+func (ev *EnumVariant) Inspect() string { return ev.Enum.Name + "." + ev.Name }
+
+// Tagged values report their enum name as type, i.e. type(Status.Pending) is "Status".
+func (ev *EnumValue) Type() ObjectType { return ObjectType(ev.Variant.Enum.Name) }
+func (ev *EnumValue) Inspect() string {
+	if len(ev.Payload) == 0 {
+		return ev.Variant.Inspect()
+	}
+
+	payload := []string{}
+	for _, value := range ev.Payload {
+		payload = append(payload, value.Inspect())
+	}
+
+	return ev.Variant.Inspect() + "(" + strings.Join(payload, ", ") + ")"
+}
+
+/*
+	Tagged values hash by enum, tag and payload. Payload values that can't key
+	a hash don't contribute to the key.
+*/
+func (ev *EnumValue) HashKey() HashKey {
+	hk := fnv.New64a()
+	hk.Write([]byte(ev.Variant.Enum.Name + "." + ev.Variant.Name))
+
+	for _, value := range ev.Payload {
+		if key, ok := HashKeyOf(value); ok { writeHashKey(hk, key) }
+	}
+
+	return HashKey{Type: ev.Type(), Value: hk.Sum64()}
+}
+
+/*
+	An Iterator yields the elements of a collection one at time; Next returns
+	false once the collection is exhausted. Iterable objects can be walked by
+	for-in loops, spread and any builtin that consumes a sequence.
+*/
+type Iterator interface {
+	Next() (Object, bool)
 }
 
+type Iterable interface {
+	Iterator() Iterator
+}
+
+/*
+	A Range is a lazy sequence of integers going from Start up to End (or down,
+	with a negative Step). Elements are computed on demand, so a range never
+	holds its elements in memory.
+*/
+type Range struct {
+	Start     int64
+	End       int64
+	Step      int64
+	Inclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	var out strings.Builder
+
+	out.WriteString(fmt.Sprintf("%d", r.Start))
+	if r.Inclusive { out.WriteString("..=") } else { out.WriteString("..") }
+	out.WriteString(fmt.Sprintf("%d", r.End))
+
+	if r.Step != 1 {
+		out.WriteString(fmt.Sprintf(" step %d", r.Step))
+	}
+
+	return out.String()
+}
+
+/*
+	Returns the number of elements of the range, and false if it doesn't fit
+	in an int64 (0..=9223372036854775807 has one too many). The distance
+	between the bounds is taken in uint64, where it can't overflow.
+*/
+func (r *Range) Length() (int64, bool) {
+	var span, step uint64
+
+	if r.Step > 0 {
+		if r.End < r.Start || r.End == r.Start && !r.Inclusive { return 0, true }
+		span, step = uint64(r.End)-uint64(r.Start), uint64(r.Step)
+	} else {
+		if r.End > r.Start || r.End == r.Start && !r.Inclusive { return 0, true }
+		span, step = uint64(r.Start)-uint64(r.End), -uint64(r.Step)
+	}
+
+	var length uint64
+	if r.Inclusive { length = span/step + 1 } else { length = (span-1)/step + 1 }
+
+	if length > math.MaxInt64 { return 0, false }
+	return int64(length), true
+}
+
+// Ranges are built with a length that fits in an int64 (see Length).
+func (r *Range) Len() int64 {
+	length, _ := r.Length()
+	return length
+}
+
+/*
+	The offset index*Step can overflow even when the element doesn't, but
+	then it wraps around to the element all the same.
+*/
+func (r *Range) At(index int64) (int64, bool) {
+	if index < 0 || index >= r.Len() { return 0, false }
+
+	return r.Start + int64(uint64(index)*uint64(r.Step)), true
+}
+
+func (r *Range) Contains(value int64) bool {
+	var offset, step uint64
+
+	if r.Step > 0 {
+		if value < r.Start { return false }
+		offset, step = uint64(value)-uint64(r.Start), uint64(r.Step)
+	} else {
+		if value > r.Start { return false }
+		offset, step = uint64(r.Start)-uint64(value), -uint64(r.Step)
+	}
+
+	if offset%step != 0 { return false }
+
+	return offset/step < uint64(r.Len())
+}
+
+func (r *Range) Iterator() Iterator { return &rangeIterator{rng: r, length: r.Len()} }
+
+type rangeIterator struct {
+	rng    *Range
+	index  int64
+	length int64
+}
+
+func (ri *rangeIterator) Next() (Object, bool) {
+	if ri.index >= ri.length { return nil, false }
+
+	value, _ := ri.rng.At(ri.index)
+	ri.index++
+
+	return &Integer{Value: value}, true
+}
+
+func (a *Array) Iterator() Iterator { return &sliceIterator{elements: a.Elements} }
+
+// Iterating over a string yields its characters (runes), not its bytes.
+func (s *String) Iterator() Iterator {
+	chars := []Object{}
+	for _, r := range s.Value {
+		chars = append(chars, &String{Value: string(r)})
+	}
+
+	return &sliceIterator{elements: chars}
+}
+
+// Iterating over a hash yields its keys, in insertion order.
+func (h *Hash) Iterator() Iterator {
+	keys := make([]Object, 0, len(h.pairs))
+	for _, pair := range h.pairs {
+		keys = append(keys, pair.Key)
+	}
+
+	return &sliceIterator{elements: keys}
+}
+
+type sliceIterator struct {
+	elements []Object
+	index    int
+}
+
+func (si *sliceIterator) Next() (Object, bool) {
+	if si.index >= len(si.elements) { return nil, false }
+
+	element := si.elements[si.index]
+	si.index++
+
+	return element, true
+}
+
+
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
 	var out strings.Builder
 
 	pairs := []string{}
 
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -98,18 +489,18 @@ func (h *Hash) Inspect() string {
 
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
-func (i *Integer) HashKey() uint64 {
-	return uint64(i.Value)
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: INTEGER_OBJ, Value: uint64(i.Value)}
 }
 
 func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
 func (b *Boolean) Inspect() string { return fmt.Sprintf("%t", b.Value) }
-func (b *Boolean) HashKey() uint64 {
+func (b *Boolean) HashKey() HashKey {
 	var value uint64
 
 	if b.Value { value = 1 } else { value = 0 }
 
-	return value
+	return HashKey{Type: BOOLEAN_OBJ, Value: value}
 }
 
 func (n *Nil) Type() ObjectType { return NIL_OBJ }
@@ -118,9 +509,46 @@ func (n *Nil) Inspect() string { return "nil" }
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
+func (tc *TailCall) Type() ObjectType { return TAIL_CALL_OBJ }
+func (tc *TailCall) Inspect() string { return "tail call to " + tc.Function.Inspect() }
+
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string { return "ERROR " + e.Message }
 
+func (e *Error) Position() string { return fmt.Sprintf("%d:%d", e.Line, e.Column) }
+
+/*
+	Renders the calls the error unwound, outermost first, each with the position
+	it was at in its function when the error was raised, or "" if it was raised
+	outside of any call:
+
+		Traceback (most recent call last):
+		  at 7:1, in <program>
+		  at 3:5, in outer
+		  at 2:14, in inner
+*/
+func (e *Error) Traceback() string {
+	if len(e.Trace) == 0 { return "" }
+
+	var out strings.Builder
+	out.WriteString("Traceback (most recent call last):\n")
+
+	caller := "<program>"
+	for i := len(e.Trace) - 1; i >= 0; i-- {
+		frame := e.Trace[i]
+		out.WriteString(fmt.Sprintf("  at %d:%d, in %s\n", frame.Line, frame.Column, caller))
+
+		caller = frame.Function
+		if caller == "" { caller = "<anonymous>" }
+	}
+	out.WriteString(fmt.Sprintf("  at %s, in %s\n", e.Position(), caller))
+
+	return out.String()
+}
+
+func (ex *Exception) Type() ObjectType { return EXCEPTION_OBJ }
+func (ex *Exception) Inspect() string { return ex.Error.Kind + ": " + ex.Error.Message }
+
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
@@ -142,11 +570,11 @@ func (f *Function) Inspect() string {
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string { return fmt.Sprintf("%q", s.Value) }
-func (s *String) HashKey() uint64 {
+func (s *String) HashKey() HashKey {
 	hk := fnv.New64a()
 	hk.Write([]byte(s.Value))
 
-	return hk.Sum64()
+	return HashKey{Type: STRING_OBJ, Value: hk.Sum64()}
 }
 
 func (bi *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"testing"
+)
 
 func TestBooleanHashKey(t *testing.T) {
 	bhka := &Boolean{Value: true}
@@ -61,3 +65,213 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestRange(t *testing.T) {
+	tests := []struct{
+		rng      *Range
+		length   int64
+		elements []int64
+	}{
+		{&Range{Start: 0, End: 5, Step: 1}, 5, []int64{0, 1, 2, 3, 4}},
+		{&Range{Start: 0, End: 5, Step: 1, Inclusive: true}, 6, []int64{0, 1, 2, 3, 4, 5}},
+		{&Range{Start: 0, End: 10, Step: 3}, 4, []int64{0, 3, 6, 9}},
+		{&Range{Start: 5, End: 0, Step: -2}, 3, []int64{5, 3, 1}},
+		{&Range{Start: 5, End: 1, Step: -2, Inclusive: true}, 3, []int64{5, 3, 1}},
+		{&Range{Start: 5, End: 0, Step: 1}, 0, []int64{}},
+	}
+
+	for _, tc := range tests {
+		if tc.rng.Len() != tc.length {
+			t.Errorf("%s has wrong length. want=%d, got=%d", tc.rng.Inspect(), tc.length, tc.rng.Len())
+		}
+
+		for i, expected := range tc.elements {
+			value, ok := tc.rng.At(int64(i))
+			if !ok || value != expected {
+				t.Errorf("%s has wrong element at %d. want=%d, got=%d", tc.rng.Inspect(), i, expected, value)
+			}
+
+			if !tc.rng.Contains(expected) {
+				t.Errorf("%s should contain %d", tc.rng.Inspect(), expected)
+			}
+		}
+
+		if _, ok := tc.rng.At(tc.length); ok {
+			t.Errorf("%s should not have an element at %d", tc.rng.Inspect(), tc.length)
+		}
+	}
+}
+
+func TestRangeBounds(t *testing.T) {
+	tests := []struct{
+		rng     *Range
+		length  int64
+		fits    bool
+		last    int64
+		outside int64
+	}{
+		{&Range{Start: 0, End: math.MaxInt64, Step: 1}, math.MaxInt64, true, math.MaxInt64 - 1, math.MaxInt64},
+		{&Range{Start: 0, End: math.MaxInt64, Step: 1, Inclusive: true}, 0, false, 0, 0},
+		{&Range{Start: math.MinInt64, End: -1, Step: 1, Inclusive: true}, 0, false, 0, 0},
+		{&Range{Start: math.MinInt64 + 1, End: -1, Step: 1, Inclusive: true}, math.MaxInt64, true, -1, 0},
+		{&Range{Start: math.MinInt64, End: math.MaxInt64, Step: 2}, 0, false, 0, 0},
+		{&Range{Start: math.MinInt64 + 1, End: math.MaxInt64, Step: 2}, math.MaxInt64, true, math.MaxInt64 - 2, math.MaxInt64},
+		{&Range{Start: math.MaxInt64, End: math.MinInt64, Step: -1}, 0, false, 0, 0},
+		{&Range{Start: math.MaxInt64, End: math.MinInt64, Step: -2, Inclusive: true}, 0, false, 0, 0},
+		{&Range{Start: math.MaxInt64, End: math.MinInt64, Step: -3}, 6148914691236517205, true, math.MinInt64 + 3, math.MinInt64},
+		{&Range{Start: math.MaxInt64, End: math.MinInt64, Step: math.MinInt64, Inclusive: true}, 2, true, -1, math.MinInt64},
+		{&Range{Start: math.MinInt64, End: math.MaxInt64, Step: math.MaxInt64, Inclusive: true}, 3, true, math.MaxInt64 - 1, math.MaxInt64},
+	}
+
+	for _, tc := range tests {
+		length, ok := tc.rng.Length()
+		if ok != tc.fits {
+			t.Errorf("%s should fit in an int64: %t", tc.rng.Inspect(), tc.fits)
+			continue
+		}
+
+		if !ok { continue }
+
+		if length != tc.length {
+			t.Errorf("%s has wrong length. want=%d, got=%d", tc.rng.Inspect(), tc.length, length)
+		}
+
+		if value, ok := tc.rng.At(length - 1); !ok || value != tc.last {
+			t.Errorf("%s has wrong last element. want=%d, got=%d", tc.rng.Inspect(), tc.last, value)
+		}
+
+		if !tc.rng.Contains(tc.last) {
+			t.Errorf("%s should contain %d", tc.rng.Inspect(), tc.last)
+		}
+
+		if tc.rng.Contains(tc.outside) {
+			t.Errorf("%s should not contain %d", tc.rng.Inspect(), tc.outside)
+		}
+	}
+}
+
+// A key every instance of which hashes alike, as keys whose hashes collide do.
+type collidingKey struct{ name string }
+
+func (c *collidingKey) Type() ObjectType { return "COLLIDING" }
+func (c *collidingKey) Inspect() string  { return c.name }
+func (c *collidingKey) HashKey() HashKey { return HashKey{Type: "COLLIDING", Value: 1} }
+func (c *collidingKey) Equal(other Object) bool {
+	o, ok := other.(*collidingKey)
+	return ok && c.name == o.name
+}
+
+func TestHashCollisions(t *testing.T) {
+	hash := NewHash(0)
+	hash.Set(&collidingKey{"a"}, &Integer{Value: 1})
+	hash.Set(&collidingKey{"b"}, &Integer{Value: 2})
+	hash.Set(&collidingKey{"a"}, &Integer{Value: 3})
+
+	if hash.Len() != 2 {
+		t.Fatalf("hash has wrong number of pairs. want=2, got=%d", hash.Len())
+	}
+
+	for name, expected := range map[string]int64{"a": 3, "b": 2} {
+		value, ok := hash.Get(&collidingKey{name})
+		if !ok || value.(*Integer).Value != expected {
+			t.Errorf("wrong value for %s. want=%d, got=%v", name, expected, value)
+		}
+	}
+
+	if _, ok := hash.Get(&collidingKey{"c"}); ok {
+		t.Errorf("expected no value for c")
+	}
+}
+
+func TestHashKeyOf(t *testing.T) {
+	one := &Integer{Value: 1}
+
+	if one.HashKey() == (&Boolean{Value: true}).HashKey() {
+		t.Errorf("1 and true have the same hash key")
+	}
+
+	pair := &Array{Elements: []Object{one, &String{Value: "a"}}}
+	same := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+	swapped := &Array{Elements: []Object{&String{Value: "a"}, one}}
+
+	pairKey, ok := HashKeyOf(pair)
+	if !ok {
+		t.Fatalf("array of hashable values has no hash key")
+	}
+
+	if sameKey, _ := HashKeyOf(same); sameKey != pairKey {
+		t.Errorf("arrays with same elements have different hash keys")
+	}
+
+	if swappedKey, _ := HashKeyOf(swapped); swappedKey == pairKey {
+		t.Errorf("arrays with different elements have same hash keys")
+	}
+
+	if _, ok := HashKeyOf(&Array{Elements: []Object{NewHash(0)}}); ok {
+		t.Errorf("array holding a hash has a hash key")
+	}
+}
+
+func TestInt64Overflow(t *testing.T) {
+	tests := []struct{
+		op       func(a, b int64) (int64, bool)
+		a, b     int64
+		expected int64
+		ok       bool
+	}{
+		{AddInt64, math.MaxInt64 - 1, 1, math.MaxInt64, true},
+		{AddInt64, math.MaxInt64, 1, 0, false},
+		{AddInt64, math.MinInt64, -1, 0, false},
+		{SubInt64, math.MinInt64 + 1, 1, math.MinInt64, true},
+		{SubInt64, math.MinInt64, 1, 0, false},
+		{SubInt64, 0, math.MinInt64, 0, false},
+		{MulInt64, 1 << 31, 1 << 31, 1 << 62, true},
+		{MulInt64, 1 << 32, 1 << 32, 0, false},
+		{MulInt64, -1, math.MinInt64, 0, false},
+		{MulInt64, math.MinInt64, -1, 0, false},
+		{MulInt64, math.MinInt64, 1, math.MinInt64, true},
+		{DivInt64, math.MinInt64, -1, 0, false},
+		{DivInt64, -7, 2, -3, true},
+	}
+
+	for i, tc := range tests {
+		result, ok := tc.op(tc.a, tc.b)
+		if ok != tc.ok || (ok && result != tc.expected) {
+			t.Errorf("tests[%d] - wrong result for %d and %d. want=%d (%t), got=%d (%t)", i, tc.a, tc.b, tc.expected, tc.ok, result, ok)
+		}
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	if _, ok := IntegerOf(big.NewInt(5)).(*Integer); !ok {
+		t.Errorf("integer fitting in 64 bits is not an Integer")
+	}
+
+	huge := IntegerOf(new(big.Int).Lsh(big.NewInt(1), 64))
+	if _, ok := huge.(*BigInteger); !ok {
+		t.Fatalf("integer out of 64 bits is not a BigInteger")
+	}
+
+	if huge.Inspect() != "18446744073709551616" {
+		t.Errorf("wrong inspect. got=%s", huge.Inspect())
+	}
+
+	small := &Integer{Value: math.MaxInt64}
+	if order, ok := Compare(small, huge); !ok || order != -1 {
+		t.Errorf("wrong order of %s and %s. got=%d", small.Inspect(), huge.Inspect(), order)
+	}
+
+	negative := IntegerOf(new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 64)))
+	if order, ok := Compare(negative, small); !ok || order != -1 {
+		t.Errorf("wrong order of %s and %s. got=%d", negative.Inspect(), small.Inspect(), order)
+	}
+
+	same := IntegerOf(new(big.Int).Lsh(big.NewInt(1), 64))
+	if !Equal(huge, same) || huge.(Hashable).HashKey() != same.(Hashable).HashKey() {
+		t.Errorf("big integers with same value are different")
+	}
+
+	if huge.(Hashable).HashKey() == negative.(Hashable).HashKey() {
+		t.Errorf("big integers of opposite signs have same hash keys")
+	}
+}
@@ -0,0 +1,149 @@
+package object
+
+import (
+	"sort"
+	"sync"
+)
+
+/*
+	A Registry holds the builtins programs can call, by name, the methods of
+	the builtin types, by type and name, and whether programs can spawn tasks.
+	Each root environment can be given a registry of its own (see
+	SetBuiltins), so that embedders add, remove or restrict builtins and
+	methods per interpreter. Bindings made by programs shadow the builtins of
+	the same name.
+*/
+type Registry struct {
+	mu       sync.RWMutex
+	builtins map[string]*Builtin
+	methods  map[ObjectType]map[string]*Builtin
+	spawn    bool
+}
+
+// Returns a registry holding a copy of builtins and methods, which lets programs spawn tasks.
+func NewRegistry(builtins map[string]*Builtin, methods map[ObjectType]map[string]*Builtin) *Registry {
+	r := &Registry{builtins: make(map[string]*Builtin, len(builtins)), methods: map[ObjectType]map[string]*Builtin{}, spawn: true}
+	for name, builtin := range builtins {
+		r.builtins[name] = builtin
+	}
+
+	for t, table := range methods {
+		for name, method := range table {
+			r.setMethod(t, name, method)
+		}
+	}
+
+	return r
+}
+
+func (r *Registry) Get(name string) (*Builtin, bool) {
+	r.mu.RLock()
+	builtin, ok := r.builtins[name]
+	r.mu.RUnlock()
+
+	return builtin, ok
+}
+
+func (r *Registry) Set(name string, builtin *Builtin) {
+	r.mu.Lock()
+	r.builtins[name] = builtin
+	r.mu.Unlock()
+}
+
+func (r *Registry) Delete(name string) {
+	r.mu.Lock()
+	delete(r.builtins, name)
+	r.mu.Unlock()
+}
+
+// Returns the method name of the values of type t.
+func (r *Registry) Method(t ObjectType, name string) (*Builtin, bool) {
+	r.mu.RLock()
+	method, ok := r.methods[t][name]
+	r.mu.RUnlock()
+
+	return method, ok
+}
+
+/*
+	Adds (or replaces) the method name to the values of type t, which can then
+	be called as x.name(args). The method gets the receiver as its first
+	argument.
+*/
+func (r *Registry) SetMethod(t ObjectType, name string, method *Builtin) {
+	r.mu.Lock()
+	r.setMethod(t, name, method)
+	r.mu.Unlock()
+}
+
+func (r *Registry) setMethod(t ObjectType, name string, method *Builtin) {
+	if r.methods[t] == nil {
+		r.methods[t] = map[string]*Builtin{}
+	}
+
+	r.methods[t][name] = method
+}
+
+func (r *Registry) DeleteMethod(t ObjectType, name string) {
+	r.mu.Lock()
+	delete(r.methods[t], name)
+	r.mu.Unlock()
+}
+
+// Reports whether programs can spawn tasks.
+func (r *Registry) CanSpawn() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.spawn
+}
+
+func (r *Registry) SetSpawn(spawn bool) {
+	r.mu.Lock()
+	r.spawn = spawn
+	r.mu.Unlock()
+}
+
+// Returns the names of the builtins, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.builtins))
+	for name := range r.builtins {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+/*
+	Returns a new registry holding only the builtins and methods of r named by
+	names, and letting programs spawn tasks only if "spawn" is one of them
+	(i.e. Restrict("len", "upper") leaves channels and tasks out of a
+	sandbox). Names r doesn't hold are ignored.
+*/
+func (r *Registry) Restrict(names ...string) *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	restricted := &Registry{builtins: make(map[string]*Builtin, len(names)), methods: map[ObjectType]map[string]*Builtin{}}
+	for _, name := range names {
+		if builtin, ok := r.builtins[name]; ok {
+			restricted.builtins[name] = builtin
+		}
+
+		for t, table := range r.methods {
+			if method, ok := table[name]; ok {
+				restricted.setMethod(t, name, method)
+			}
+		}
+
+		if name == "spawn" {
+			restricted.spawn = r.spawn
+		}
+	}
+
+	return restricted
+}
@@ -0,0 +1,35 @@
+package object
+
+import (
+	"io"
+	"os"
+)
+
+/*
+	A Runtime holds the streams builtins do I/O with (see Builtin.RuntimeFn).
+	Streams left nil are those of the process, as are all of them for a nil
+	*Runtime.
+*/
+type Runtime struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  io.Reader
+}
+
+func (r *Runtime) Out() io.Writer {
+	if r == nil || r.Stdout == nil { return os.Stdout }
+
+	return r.Stdout
+}
+
+func (r *Runtime) Err() io.Writer {
+	if r == nil || r.Stderr == nil { return os.Stderr }
+
+	return r.Stderr
+}
+
+func (r *Runtime) In() io.Reader {
+	if r == nil || r.Stdin == nil { return os.Stdin }
+
+	return r.Stdin
+}
@@ -0,0 +1,92 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+/*
+	A Sandbox bounds the evaluation of a program: the number of steps it takes,
+	the depth of the calls in progress, the bytes it allocates (approximately)
+	and the time it runs for, through ctx. A zero limit means no limit.
+
+	Limits are hit with errors of the LimitError kind, which try can't catch.
+	Once a limit is hit the sandbox stays stopped, and every step taken after
+	that fails the same way, so that the program unwinds whatever it's doing,
+	spawned tasks and generators included. The counters are shared by all of
+	them.
+*/
+type Sandbox struct {
+	ctx       context.Context
+	maxSteps  int64
+	maxDepth  int64
+	maxMemory int64
+
+	steps     atomic.Int64
+	depth     atomic.Int64
+	allocated atomic.Int64
+	stopped   atomic.Pointer[string] // Why the sandbox stopped, nil while it runs
+}
+
+func NewSandbox(ctx context.Context, maxSteps, maxDepth, maxMemory int64) *Sandbox {
+	return &Sandbox{ctx: ctx, maxSteps: maxSteps, maxDepth: maxDepth, maxMemory: maxMemory}
+}
+
+// How often, in steps, the context is checked for cancellation.
+const contextCheckInterval = 1024
+
+// Counts an evaluation step, failing once the sandbox is stopped.
+func (s *Sandbox) Step() *Error {
+	if s.stopped.Load() != nil { return s.err() }
+
+	steps := s.steps.Add(1)
+	if s.maxSteps > 0 && steps > s.maxSteps {
+		return s.Stop(fmt.Sprintf("step limit exceeded: %d steps", s.maxSteps))
+	}
+
+	if steps%contextCheckInterval == 0 && s.ctx.Err() != nil { return s.Cancel() }
+
+	return nil
+}
+
+// Counts a call entered, to be left with Leave unless entering it failed.
+func (s *Sandbox) Enter() *Error {
+	if depth := s.depth.Add(1); s.maxDepth > 0 && depth > s.maxDepth {
+		s.depth.Add(-1)
+		return s.Stop(fmt.Sprintf("call depth limit exceeded: %d calls", s.maxDepth))
+	}
+
+	return nil
+}
+
+func (s *Sandbox) Leave() { s.depth.Add(-1) }
+
+// Counts bytes about to be allocated, failing if they go over the budget.
+func (s *Sandbox) Allocate(bytes int64) *Error {
+	if s.stopped.Load() != nil { return s.err() }
+
+	if s.maxMemory > 0 && (bytes > s.maxMemory || s.allocated.Add(bytes) > s.maxMemory) {
+		return s.Stop(fmt.Sprintf("memory limit exceeded: %d bytes", s.maxMemory))
+	}
+
+	return nil
+}
+
+/*
+	Stops the sandbox for reason, unless it was stopped already, and returns
+	the error the program is stopped with.
+*/
+func (s *Sandbox) Stop(reason string) *Error {
+	s.stopped.CompareAndSwap(nil, &reason)
+	return s.err()
+}
+
+// Stops the sandbox because its context is done.
+func (s *Sandbox) Cancel() *Error {
+	return s.Stop("evaluation stopped: " + s.ctx.Err().Error())
+}
+
+func (s *Sandbox) err() *Error {
+	return &Error{Message: *s.stopped.Load(), Kind: "LimitError", Code: "limit_exceeded"}
+}
@@ -0,0 +1,486 @@
+package optimizer
+
+import (
+	"dux/ast"
+	"dux/evaluator"
+	"dux/object"
+	"dux/token"
+	"strconv"
+)
+
+/*
+	The optimizer rewrites an *ast.Program into a cheaper one that evaluates
+	to the same result, in place:
+
+	- operators over integer, string and boolean literals are folded into the
+	  literal they evaluate to, unless they fail, which is left to run time;
+	- if branches that can't be taken are dropped, and the taken branch of an
+	  if statement is spliced into the enclosing block, which it shares the
+	  scope of;
+	- statements after a return or a throw are dropped;
+	- variables bound once in their scope to a literal are replaced by the
+	  literal where they're read after the let. The let itself is kept, for
+	  closures created before it and for later programs sharing the
+	  environment (i.e. the lines of the REPL), and globals are only inlined
+	  outside of functions, which may run after another program rebinds them.
+
+	Folding goes through the evaluator's own operators, so that the result is
+	the same down to its quirks: a computed 0 is truthy, unlike the literal,
+	so it's never folded into one.
+*/
+
+// Folded strings longer than this are left to be built at run time.
+const maxFoldedString = 1024
+
+type scope struct {
+	declared  map[string]int           // How many bindings of each name the scope has
+	constants map[string]ast.Expression // Literal value of the names bound once, once bound
+	function  bool
+	outer     *scope
+}
+
+type optimizer struct {
+	scope *scope
+}
+
+func Optimize(program *ast.Program) *ast.Program {
+	o := &optimizer{}
+
+	o.pushScope(false)
+	o.declareAll(program.Statements)
+	program.Statements = o.statements(program.Statements)
+
+	return program
+}
+
+func (o *optimizer) pushScope(function bool) {
+	o.scope = &scope{declared: map[string]int{}, constants: map[string]ast.Expression{}, function: function, outer: o.scope}
+}
+
+func (o *optimizer) popScope() { o.scope = o.scope.outer }
+
+func (o *optimizer) declare(ident *ast.Identifier) { o.scope.declared[ident.Value]++ }
+
+// Counts the bindings made by statements in the current scope, nested if and try blocks included.
+func (o *optimizer) declareAll(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			o.declare(stmt.Name)
+			o.declareNested(stmt.Value)
+		case *ast.StructStatement:
+			o.declare(stmt.Name)
+		case *ast.EnumStatement:
+			o.declare(stmt.Name)
+		case *ast.ExpressionStatement:
+			o.declareNested(stmt.Expression)
+		}
+	}
+}
+
+func (o *optimizer) declareNested(expression ast.Expression) {
+	switch node := expression.(type) {
+	case *ast.IfExpression:
+		o.declareBlock(node.Consequence)
+		o.declareBlock(node.Alternative)
+	case *ast.TryExpression:
+		o.declareBlock(node.Block)
+		o.declareBlock(node.Finally)
+	}
+}
+
+func (o *optimizer) declareBlock(block *ast.BlockStatement) {
+	if block != nil {
+		o.declareAll(block.Statements)
+	}
+}
+
+/*
+	Optimizes the statements of a scope's own block, where lets are run
+	unconditionally and may be inlined.
+*/
+func (o *optimizer) statements(statements []ast.Statement) []ast.Statement {
+	out := []ast.Statement{}
+
+	for i, stmt := range statements {
+		stmt = o.statement(stmt)
+
+		if let, ok := stmt.(*ast.LetStatement); ok && isLiteral(let.Value) && o.scope.declared[let.Name.Value] == 1 {
+			o.scope.constants[let.Name.Value] = let.Value
+		}
+
+		out = o.splice(out, stmt, i == len(statements)-1)
+
+		if abrupt(out) { break }
+	}
+
+	return out
+}
+
+// Optimizes the statements of a nested block, sharing the scope of the enclosing one.
+func (o *optimizer) block(block *ast.BlockStatement) {
+	if block == nil { return }
+
+	out := []ast.Statement{}
+
+	for i, stmt := range block.Statements {
+		stmt = o.statement(stmt)
+		out = o.splice(out, stmt, i == len(block.Statements)-1)
+
+		if abrupt(out) { break }
+	}
+
+	block.Statements = out
+}
+
+// Optimizes the body of a scope, where bind declares the names bound on entering it.
+func (o *optimizer) scopeBlock(block *ast.BlockStatement, function bool, bind func()) {
+	o.pushScope(function)
+	bind()
+
+	if block != nil {
+		o.declareAll(block.Statements)
+		block.Statements = o.statements(block.Statements)
+	}
+
+	o.popScope()
+}
+
+/*
+	Appends stmt to statements, replacing an if statement whose condition is
+	known by the statements of the branch taken. An if that isn't taken and has
+	no else only leaves nil behind when it's the last statement, whose value
+	the block evaluates to, and so do literals.
+*/
+func (o *optimizer) splice(statements []ast.Statement, stmt ast.Statement, last bool) []ast.Statement {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok { return append(statements, stmt) }
+
+	if isLiteral(es.Expression) && !last { return statements }
+
+	ifex, ok := es.Expression.(*ast.IfExpression)
+	if !ok { return append(statements, stmt) }
+
+	taken, known := condition(ifex.Condition)
+	if !known { return append(statements, stmt) }
+
+	branch := ifex.Alternative
+	if taken { branch = ifex.Consequence }
+
+	switch {
+	case branch != nil && len(branch.Statements) > 0:
+		return append(statements, branch.Statements...)
+	case last && branch == nil:
+		return append(statements, &ast.ExpressionStatement{Token: ifex.Token, Expression: nilLiteral(ifex.Token)})
+	case last:
+		return append(statements, stmt) // An empty block evaluates to no value at all
+	default:
+		return statements
+	}
+}
+
+// Reports whether the last of statements always cuts the block short.
+func abrupt(statements []ast.Statement) bool {
+	if len(statements) == 0 { return false }
+
+	switch statements[len(statements)-1].(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	}
+
+	return false
+}
+
+func (o *optimizer) statement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		stmt.Expression = o.expression(stmt.Expression)
+	case *ast.LetStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = o.expression(stmt.ReturnValue)
+	case *ast.ThrowStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.YieldStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.StructStatement:
+		for _, method := range stmt.Methods {
+			o.function(method.Function)
+		}
+	}
+
+	return stmt
+}
+
+func (o *optimizer) function(fn *ast.FunctionLiteral) {
+	o.scopeBlock(fn.Body, true, func() {
+		for _, param := range fn.Parameters {
+			o.declare(param)
+		}
+	})
+}
+
+func (o *optimizer) expressions(expressions []ast.Expression) {
+	for i, expression := range expressions {
+		expressions[i] = o.expression(expression)
+	}
+}
+
+func (o *optimizer) expression(expression ast.Expression) ast.Expression {
+	switch node := expression.(type) {
+	case *ast.Identifier:
+		return o.identifier(node)
+	case *ast.PrefixExpression:
+		node.Right = o.expression(node.Right)
+
+		if isLiteral(node.Right) {
+			if folded := fold(evaluator.PrefixOperation(node.Operator, value(node.Right)), node.Token); folded != nil {
+				return folded
+			}
+		}
+	case *ast.InfixExpression:
+		node.Left = o.expression(node.Left)
+		node.Right = o.expression(node.Right)
+
+		if isLiteral(node.Left) && isLiteral(node.Right) && !oversized(node) {
+			folded := fold(evaluator.InfixOperation(node.Operator, value(node.Left), value(node.Right)), literalToken(node.Left))
+			if folded != nil { return folded }
+		}
+	case *ast.IfExpression:
+		return o.ifExpression(node)
+	case *ast.FunctionLiteral:
+		o.function(node)
+	case *ast.CallExpression:
+		o.call(node)
+	case *ast.ArrayLiteral:
+		o.expressions(node.Elements)
+	case *ast.HashLiteral:
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key = o.expression(pair.Key)
+			if pair.Value != nil { node.Pairs[i].Value = o.expression(pair.Value) }
+		}
+	case *ast.IndexExpresssion:
+		node.Left = o.expression(node.Left)
+		node.Index = o.expression(node.Index)
+	case *ast.MemberExpression:
+		node.Left = o.expression(node.Left)
+	case *ast.SpreadElement:
+		node.Value = o.expression(node.Value)
+	case *ast.PropagateExpression:
+		node.Value = o.expression(node.Value)
+	case *ast.RangeExpression:
+		node.Start = o.expression(node.Start)
+		node.End = o.expression(node.End)
+		if node.Step != nil {
+			node.Step = o.expression(node.Step)
+		}
+	case *ast.ForExpression:
+		node.Iterable = o.expression(node.Iterable)
+		o.scopeBlock(node.Body, false, func() { o.declare(node.Variable) })
+	case *ast.MatchExpression:
+		node.Subject = o.expression(node.Subject)
+
+		for _, arm := range node.Arms {
+			// Patterns are left as written, names in them may be enum variants
+			o.scopeBlock(arm.Body, false, func() { o.declarePattern(arm.Pattern) })
+		}
+	case *ast.TryExpression:
+		o.block(node.Block)
+
+		if node.Catch != nil {
+			o.scopeBlock(node.Catch, false, func() {
+				if node.CatchParam != nil { o.declare(node.CatchParam) }
+			})
+		}
+
+		o.block(node.Finally)
+	case *ast.SpawnExpression:
+		o.call(node.Call)
+	case *ast.SelectExpression:
+		for _, selectCase := range node.Cases {
+			if selectCase.Operation != nil {
+				o.expressions(selectCase.Operation.Arguments)
+			}
+
+			o.scopeBlock(selectCase.Body, false, func() {
+				if selectCase.Binding != nil { o.declare(selectCase.Binding) }
+			})
+		}
+	}
+
+	return expression
+}
+
+func (o *optimizer) call(node *ast.CallExpression) {
+	node.Function = o.expression(node.Function)
+	o.expressions(node.Arguments)
+}
+
+func (o *optimizer) declarePattern(pattern ast.Expression) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		o.declare(pattern)
+	case *ast.CallExpression:
+		for _, arg := range pattern.Arguments {
+			if binding, ok := arg.(*ast.Identifier); ok {
+				o.declare(binding)
+			}
+		}
+	}
+}
+
+/*
+	Drops the branch of an if expression that can't be taken. An expression
+	left with a single branch holding a single expression is replaced by it.
+*/
+func (o *optimizer) ifExpression(node *ast.IfExpression) ast.Expression {
+	node.Condition = o.expression(node.Condition)
+	o.block(node.Consequence)
+	o.block(node.Alternative)
+
+	taken, known := condition(node.Condition)
+	if !known { return node }
+
+	branch := node.Alternative
+	if taken { branch = node.Consequence }
+
+	if branch == nil { return nilLiteral(node.Token) }
+
+	if len(branch.Statements) == 1 {
+		if es, ok := branch.Statements[0].(*ast.ExpressionStatement); ok && es.Expression != nil {
+			return es.Expression
+		}
+	}
+
+	node.Condition = &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true", Line: node.Token.Line, Column: node.Token.Column}, Value: true}
+	node.Consequence, node.Alternative = branch, nil
+
+	return node
+}
+
+// Replaces a variable bound to a literal by the literal.
+func (o *optimizer) identifier(node *ast.Identifier) ast.Expression {
+	if node.TokenLiteral() == "nil" { return node }
+
+	crossed := false
+	for s := o.scope; s != nil; s = s.outer {
+		if s.declared[node.Value] > 0 {
+			constant, ok := s.constants[node.Value]
+			if !ok || (crossed && s.outer == nil) { return node }
+
+			return relocate(constant, node.Token)
+		}
+
+		crossed = crossed || s.function
+	}
+
+	return node
+}
+
+// Reports whether the condition of an if is known, and if so whether it holds.
+func condition(expression ast.Expression) (taken bool, known bool) {
+	if !isLiteral(expression) { return false, false }
+
+	return evaluator.Truthy(value(expression)), true
+}
+
+func isLiteral(expression ast.Expression) bool {
+	switch node := expression.(type) {
+	case *ast.IntegerLiteral:
+		// Literals too large for 64 bits are left to run, failing in strict mode
+		return node.Big == nil
+	case *ast.StringLiteral, *ast.Boolean:
+		return true
+	case *ast.Identifier:
+		return node.TokenLiteral() == "nil"
+	}
+
+	return false
+}
+
+/*
+	Reports whether a string repetition of literals would be longer than
+	maxFoldedString, which is checked before building it since fold would
+	only throw it away.
+*/
+func oversized(node *ast.InfixExpression) bool {
+	if node.Operator != "*" { return false }
+
+	str, ok := node.Left.(*ast.StringLiteral)
+	count, isCount := node.Right.(*ast.IntegerLiteral)
+	if !ok {
+		str, ok = node.Right.(*ast.StringLiteral)
+		count, isCount = node.Left.(*ast.IntegerLiteral)
+	}
+
+	if !ok || !isCount || len(str.Value) == 0 { return false }
+
+	return count.Value > int64(maxFoldedString/len(str.Value))
+}
+
+// The object a literal evaluates to.
+func value(literal ast.Expression) object.Object {
+	return evaluator.Eval(literal, nil)
+}
+
+func literalToken(literal ast.Expression) token.Token {
+	switch node := literal.(type) {
+	case *ast.IntegerLiteral:
+		return node.Token
+	case *ast.StringLiteral:
+		return node.Token
+	case *ast.Boolean:
+		return node.Token
+	case *ast.Identifier:
+		return node.Token
+	}
+
+	return token.Token{}
+}
+
+// Returns the literal obj stands for, positioned at tok, or nil if it has none.
+func fold(obj object.Object, tok token.Token) ast.Expression {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		if obj.Value == 0 { return nil }
+
+		literal := strconv.FormatInt(obj.Value, 10)
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal, Line: tok.Line, Column: tok.Column}, Value: obj.Value}
+	case *object.String:
+		if len(obj.Value) > maxFoldedString { return nil }
+
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: obj.Value, Line: tok.Line, Column: tok.Column}, Value: obj.Value}
+	case *object.Boolean:
+		literal := token.Token{Type: token.FALSE, Literal: "false", Line: tok.Line, Column: tok.Column}
+		if obj.Value {
+			literal.Type, literal.Literal = token.TRUE, "true"
+		}
+
+		return &ast.Boolean{Token: literal, Value: obj.Value}
+	}
+
+	return nil
+}
+
+func relocate(literal ast.Expression, tok token.Token) ast.Expression {
+	switch node := literal.(type) {
+	case *ast.IntegerLiteral:
+		copied := *node
+		copied.Token.Line, copied.Token.Column = tok.Line, tok.Column
+		return &copied
+	case *ast.StringLiteral:
+		copied := *node
+		copied.Token.Line, copied.Token.Column = tok.Line, tok.Column
+		return &copied
+	case *ast.Boolean:
+		copied := *node
+		copied.Token.Line, copied.Token.Column = tok.Line, tok.Column
+		return &copied
+	}
+
+	return nilLiteral(tok)
+}
+
+func nilLiteral(tok token.Token) ast.Expression {
+	return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "nil", Line: tok.Line, Column: tok.Column}, Value: "nil"}
+}
@@ -0,0 +1,49 @@
+package optimizer
+
+import (
+	"dux/lexer"
+	"dux/parser"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"1 * 100 + 0", "100"},
+		{`"a" + "b"`, "ab"},
+		{`"ab" * 2`, "abab"},
+		{`"x" * 500000000`, "(x * 500000000)"},
+		{`let s = "ab"; 500000000 * s`, "let s = ab;(500000000 * ab)"},
+		{"!true", "false"},
+		{"1 < 2 == true", "true"},
+		{"1 - 1", "(1 - 1)"},
+		{"1 + true", "(1 + true)"},
+		{"5 / 0", "(5 / 0)"},
+		{"if (true) { 1 } else { 2 }", "1"},
+		{"if (false) { 1 }", "nil"},
+		{"if (0) { 1 } else { 2 }; 3", "3"},
+		{"let y = if (1 < 2) { 10 } else { 20 }", "let y = 10;"},
+		{"let f = fn() { return 1; 2 }", "let f = fn() { return 1;};"},
+		{"let f = fn(c) { if (true) { return c; } c + 1 }", "let f = fn(c) { return c;};"},
+		{"let f = fn(a) { if (true) { let b = 1; a + b } }", "let f = fn(a) { let b = 1;(a + b)};"},
+		{"1; 2; puts(3); 4", "puts(3)4"},
+		{"let x = 2; x * 3", "let x = 2;6"},
+		{"let x = 2; let f = fn() { x }", "let x = 2;let f = fn() { x};"},
+		{"let f = fn() { let x = 2; fn() { x } }", "let f = fn() { let x = 2;fn() { 2}};"},
+		{"for (i in 0..3) { let k = 2; i * k }", "for (i in (0..3)) let k = 2;(i * 2)"},
+		{"let x = 1; let x = 2; x", "let x = 1;let x = 2;x"},
+		{"let f = fn(x) { let x = 1; x }", "let f = fn(x) { let x = 1;x};"},
+		{"let len = 1; len", "let len = 1;1"},
+		{"let f = fn() { let y = x; let x = 1; y + x }", "let f = fn() { let y = x;let x = 1;(y + 1)};"},
+	}
+
+	for _, tc := range tests {
+		program := Optimize(parser.New(lexer.New(tc.input)).ParseProgram())
+
+		if program.String() != tc.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tc.input, tc.expected, program.String())
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"dux/lexer"
 	"dux/token"
 	"fmt"
+	"math/big"
 	"strconv"
 )
 
@@ -12,7 +13,8 @@ const (
 	_ int = iota
 	LOWEST
 	EQUALS      // ==
-	LESSGREATER // > or <
+	LESSGREATER // >, <, >= or <=
+	RANGE       // start..end
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -expression or !expression
@@ -26,13 +28,20 @@ var precedences = map[token.TokenType]int {
 	token.NEQUAL: EQUALS,
 	token.GTHAN: LESSGREATER,
 	token.STHAN: LESSGREATER,
+	token.GTHANEQ: LESSGREATER,
+	token.STHANEQ: LESSGREATER,
+	token.IN: LESSGREATER,
+	token.DOTDOT: RANGE,
+	token.DOTDOTEQ: RANGE,
 	token.PLUS: SUM,
 	token.MINUS: SUM,
 	token.EXCLAMATION: PREFIX,
 	token.STAR: PRODUCT,
 	token.RBAR: PRODUCT,
 	token.LPAREN: CALL,
+	token.QUESTION: CALL,
 	token.LBRACKET: INDEX,
+	token.DOT: INDEX,
 }
 
 /*
@@ -48,6 +57,8 @@ type Parser struct {
 
 	errors []string
 
+	functions []*ast.FunctionLiteral // Functions being parsed, innermost last
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -112,12 +123,12 @@ func (p *Parser) parseExpressionList(edge token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListElement())
 	}
 
 	if !p.expectPeek(edge) {
@@ -127,11 +138,39 @@ func (p *Parser) parseExpressionList(edge token.TokenType) []ast.Expression {
 	return list
 }
 
+/*
+Parses a single element of an expression list, which is either a plain expression
+or a spread element (i.e. ...expression) when the current token is an ellipsis.
+*/
+func (p *Parser) parseListElement() ast.Expression {
+	if p.currentTokenIs(token.ELLIPSIS) {
+		return p.parseSpreadElement()
+	}
+
+	return p.parseExpression(LOWEST)
+}
+
+func (p *Parser) parseSpreadElement() *ast.SpreadElement {
+	spread := &ast.SpreadElement{Token: p.currentToken}
+
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+
+	return spread
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.currentToken}
 
 	parsedLiteral, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
 
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		if value, ok := new(big.Int).SetString(p.currentToken.Literal, 0); ok {
+			lit.Big = value
+			return lit
+		}
+	}
+
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.currentToken.Literal)
 		p.errors = append(p.errors, msg)
@@ -199,22 +238,395 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+/*
+Parses start..end and start..=end ranges. The step is optional and introduced by
+the contextual 'step' word, so 'step' is still a valid identifier elsewhere.
+*/
+func (p *Parser) parseRangeExpression(start ast.Expression) ast.Expression {
+	expression := &ast.RangeExpression{
+		Token: p.currentToken,
+		Start: start,
+		Inclusive: p.currentTokenIs(token.DOTDOTEQ),
+	}
+
+	p.nextToken()
+	expression.End = p.parseExpression(RANGE)
+
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "step" {
+		p.nextToken()
+		p.nextToken()
+
+		expression.Step = p.parseExpression(RANGE)
+	}
+
+	return expression
+}
+
+/*
+Parses an enum declaration, a comma separated list of variants where each one
+may carry a payload, i.e. enum Status { Pending, Settled(amount) }
+*/
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	stmt := &ast.EnumStatement{Token: p.currentToken}
+
+	if !p.expectPeek(token.IDENT) { return nil }
+
+	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) { return nil }
+
+	declared := map[string]bool{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) { return nil }
+
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}}
+
+		if declared[variant.Name.Value] {
+			msg := fmt.Sprintf("duplicate variant %s in enum %s", variant.Name.Value, stmt.Name.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		declared[variant.Name.Value] = true
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			variant.Fields = p.parseFunctionParameters()
+		}
+
+		stmt.Variants = append(stmt.Variants, variant)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	return stmt
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) { return nil }
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) { return nil }
+
+	if !p.expectPeek(token.LBRACE) { return nil }
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		pattern := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.FATARROW) { return nil }
+
+		arm := &ast.MatchArm{Token: p.currentToken, Pattern: pattern}
+		arm.Body = p.parseArmBody()
+
+		expression.Arms = append(expression.Arms, arm)
+
+		// Arms may be separated by commas, which are optional after block bodies
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) && !p.currentTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA)
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	return expression
+}
+
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	expression := &ast.SpawnExpression{Token: p.currentToken}
+
+	p.nextToken()
+
+	call, ok := p.parseExpression(PREFIX).(*ast.CallExpression)
+	if !ok {
+		p.errors = append(p.errors, "expected function call after spawn")
+		return nil
+	}
+
+	expression.Call = call
+
+	return expression
+}
+
+/*
+Parses select { v = recv(a) => ..., send(b, x) => ..., _ => ... }, whose cases
+are laid out like match arms.
+*/
+func (p *Parser) parseSelectExpression() ast.Expression {
+	expression := &ast.SelectExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LBRACE) { return nil }
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		var binding *ast.Identifier
+		if p.currentTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+			binding = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+			p.nextToken()
+			p.nextToken()
+		}
+
+		operation := p.parseExpression(LOWEST)
+		if !p.validSelectOperation(operation, binding) { return nil }
+
+		if !p.expectPeek(token.FATARROW) { return nil }
+
+		selectCase := &ast.SelectCase{Token: p.currentToken, Binding: binding}
+		if call, ok := operation.(*ast.CallExpression); ok {
+			selectCase.Operation = call
+		}
+		selectCase.Body = p.parseArmBody()
+
+		expression.Cases = append(expression.Cases, selectCase)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) && !p.currentTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA)
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	return expression
+}
+
+// Select cases must be recv(channel), send(channel, value) or '_'.
+func (p *Parser) validSelectOperation(operation ast.Expression, binding *ast.Identifier) bool {
+	if operation == nil { return false }
+
+	if ident, ok := operation.(*ast.Identifier); ok && ident.Value == "_" && binding == nil {
+		return true
+	}
+
+	if call, ok := operation.(*ast.CallExpression); ok {
+		switch call.Function.String() {
+		case "recv":
+			if len(call.Arguments) == 1 { return true }
+		case "send":
+			if len(call.Arguments) == 2 && binding == nil { return true }
+		}
+	}
+
+	p.errors = append(p.errors, fmt.Sprintf("expected recv(channel), send(channel, value) or _ in select, got %s", operation))
+
+	return false
+}
+
+/*
+An arm body is either a block or a single expression, which is wrapped into a
+block so that both forms evaluate the same way. A hash literal used as a single
+expression body must be wrapped in parentheses.
+*/
+func (p *Parser) parseArmBody() *ast.BlockStatement {
+	p.nextToken()
+
+	if p.currentTokenIs(token.LBRACE) {
+		return p.parseBlockStatement()
+	}
+
+	stmt := &ast.ExpressionStatement{Token: p.currentToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+
+	return &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+}
+
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) { return nil }
+
+	if !p.expectPeek(token.IDENT) { return nil }
+
+	expression.Variable = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.IN) { return nil }
+
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) { return nil }
+
+	if !p.expectPeek(token.LBRACE) { return nil }
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.currentToken}
 
-	if !p.expectPeek(token.LPAREN) {
+	if !p.parseFunctionDefinition(lit) {
 		return nil
 	}
 
+	return lit
+}
+
+/*
+Parses the parameter list and the body of a function, from the token right
+before the opening parenthesis up to the closing brace. It's shared by function
+literals and struct methods.
+*/
+func (p *Parser) parseFunctionDefinition(lit *ast.FunctionLiteral) bool {
+	if !p.expectPeek(token.LPAREN) {
+		return false
+	}
+
 	lit.Parameters = p.parseFunctionParameters()
 
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+
+		lit.ReturnType = p.parseTypeName()
+	}
+
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return false
 	}
 
+	p.functions = append(p.functions, lit)
 	lit.Body = p.parseBlockStatement()
+	p.functions = p.functions[:len(p.functions)-1]
 
-	return lit
+	markTailCalls(lit.Body, true)
+
+	return true
+}
+
+/*
+Marks the calls whose value is returned as is by the enclosing function, so
+they can run without growing the stack: the values of return statements and,
+when the block is itself in tail position, its last expression. Nested
+functions mark their own bodies, and try blocks are skipped since a call
+escaping them would escape their catch and finally clauses too.
+*/
+func markTailCalls(block *ast.BlockStatement, tail bool) {
+	if block == nil { return }
+
+	for i, stmt := range block.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ReturnStatement:
+			markTailExpression(stmt.ReturnValue, true)
+		case *ast.LetStatement:
+			markTailExpression(stmt.Value, false)
+		case *ast.ExpressionStatement:
+			markTailExpression(stmt.Expression, tail && i == len(block.Statements)-1)
+		}
+	}
+}
+
+func markTailExpression(expression ast.Expression, tail bool) {
+	switch expression := expression.(type) {
+	case *ast.CallExpression:
+		expression.IsTail = tail
+	case *ast.IfExpression:
+		markTailCalls(expression.Consequence, tail)
+		markTailCalls(expression.Alternative, tail)
+	case *ast.MatchExpression:
+		for _, arm := range expression.Arms {
+			markTailCalls(arm.Body, tail)
+		}
+	case *ast.SelectExpression:
+		for _, selectCase := range expression.Cases {
+			markTailCalls(selectCase.Body, tail)
+		}
+	case *ast.ForExpression:
+		markTailCalls(expression.Body, false)
+	}
+}
+
+func (p *Parser) parsePropagateExpression(value ast.Expression) ast.Expression {
+	return &ast.PropagateExpression{Token: p.currentToken, Value: value}
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.currentToken, Left: left}
+
+	if !p.expectPeek(token.IDENT) { return nil }
+
+	exp.Member = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	return exp
+}
+
+/*
+Parses a struct declaration: a comma separated list of field names followed by
+any number of methods, i.e. struct Name { a, b fn method(self) { ... } }
+*/
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	stmt := &ast.StructStatement{Token: p.currentToken}
+
+	if !p.expectPeek(token.IDENT) { return nil }
+
+	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) { return nil }
+
+	declared := map[string]bool{}
+
+	p.nextToken()
+
+	for !p.currentTokenIs(token.RBRACE) {
+		var name *ast.Identifier
+
+		switch p.currentToken.Type {
+		case token.IDENT:
+			name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+			stmt.Fields = append(stmt.Fields, name)
+		case token.FUNCTION:
+			method := &ast.MethodDefinition{Token: p.currentToken}
+
+			if !p.expectPeek(token.IDENT) { return nil }
+
+			name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+			method.Name = name
+			method.Function = &ast.FunctionLiteral{Token: method.Token, Name: stmt.Name.Value + "." + name.Value}
+
+			if !p.parseFunctionDefinition(method.Function) { return nil }
+
+			stmt.Methods = append(stmt.Methods, method)
+		default:
+			msg := fmt.Sprintf("expected field or method in struct %s, got %s instead", stmt.Name.Value, p.currentToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+
+		if declared[name.Value] {
+			msg := fmt.Sprintf("duplicate member %s in struct %s", name.Value, stmt.Name.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		declared[name.Value] = true
+
+		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+
+		p.nextToken()
+
+		if p.currentTokenIs(token.EOF) {
+			p.peekError(token.RBRACE)
+			return nil
+		}
+	}
+
+	return stmt
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
@@ -274,6 +686,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	p.nextToken()
 
 	current := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	current.Type = p.parseTypeAnnotation()
 	identifiers = append(identifiers, current)
 
 	for p.peekTokenIs(token.COMMA) {
@@ -281,6 +694,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		p.nextToken()
 
 		current := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		current.Type = p.parseTypeAnnotation()
 		identifiers = append(identifiers, current)
 	}
 
@@ -291,6 +705,32 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return identifiers
 }
 
+/*
+Parses an optional ': type' annotation right after the current token, returning
+nil when there's none.
+*/
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	if !p.peekTokenIs(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+
+	return p.parseTypeName()
+}
+
+func (p *Parser) parseTypeName() *ast.TypeAnnotation {
+	// 'fn' is lexed as a keyword, but it's also the name of the function type
+	if !p.peekTokenIs(token.IDENT) && !p.peekTokenIs(token.FUNCTION) {
+		p.peekError(token.IDENT)
+		return nil
+	}
+
+	p.nextToken()
+
+	return &ast.TypeAnnotation{Token: p.currentToken, Name: p.currentToken.Literal}
+}
+
 func (p *Parser) peekPrecedence() int {
 	if precedence, ok := precedences[p.peekToken.Type]; ok {
 		return precedence
@@ -326,6 +766,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(token.SELECT, p.parseSelectExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -336,8 +781,15 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NEQUAL, p.parseInfixExpression)
 	p.registerInfix(token.GTHAN, p.parseInfixExpression)
 	p.registerInfix(token.STHAN, p.parseInfixExpression)
+	p.registerInfix(token.GTHANEQ, p.parseInfixExpression)
+	p.registerInfix(token.STHANEQ, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.QUESTION, p.parsePropagateExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.DOTDOT, p.parseRangeExpression)
+	p.registerInfix(token.DOTDOTEQ, p.parseRangeExpression)
 
 	p.nextToken()
 	p.nextToken() // Shift ahead two times, to read and set the tokens.
@@ -351,10 +803,19 @@ func (p *Parser) Errors() []string {
 
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.currentToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
+
+		if p.currentTokenIs(token.ELLIPSIS) {
+			hash.Pairs = append(hash.Pairs, ast.HashPair{Key: p.parseSpreadElement()})
+
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			continue
+		}
+
 		key := p.parseExpression(LOWEST)
 
 		if !p.expectPeek(token.COLON) {
@@ -364,7 +825,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
 		// peekToken needs to be either a rbrace or a comma, or it's a invalid exp
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
@@ -399,6 +860,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	}
 
 	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	stmt.Name.Type = p.parseTypeAnnotation()
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -408,6 +870,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fn.Name = stmt.Name.Value
+	}
+
 	for p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -429,6 +895,86 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.currentToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// Parses yield <expression>, turning the enclosing function into a generator.
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	stmt := &ast.YieldStatement{Token: p.currentToken}
+
+	if len(p.functions) == 0 {
+		p.errors = append(p.errors, "yield outside of a function")
+	} else {
+		p.functions[len(p.functions)-1].IsGenerator = true
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+/*
+Parses try { ... } catch (e) { ... } finally { ... }, where the catch parameter
+is optional and either the catch or the finally clause may be left out.
+*/
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LBRACE) { return nil }
+
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+
+			if !p.expectPeek(token.IDENT) { return nil }
+
+			expression.CatchParam = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+			if !p.expectPeek(token.RPAREN) { return nil }
+		}
+
+		if !p.expectPeek(token.LBRACE) { return nil }
+
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) { return nil }
+
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.errors = append(p.errors, "expected catch or finally after try block")
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefixFn := p.prefixParseFns[p.currentToken.Type]
 
@@ -512,6 +1058,20 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.STRUCT:
+		if stmt := p.parseStructStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
+	case token.ENUM:
+		if stmt := p.parseEnumStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	default:
 		return p.parseExpressionStatement()
 	}
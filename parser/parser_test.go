@@ -37,7 +37,9 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
+
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -77,7 +79,9 @@ func TestParsingHashLiteralsBooleanKeys(t *testing.T) {
 		false: "not ok",
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
+
 		bol, ok := key.(*ast.Boolean)
 		if !ok {
 			t.Errorf("hash exp's key is not *ast.Boolean. got=%T", key)
@@ -113,7 +117,9 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 		3: "three",
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
+
 		il, ok := key.(*ast.IntegerLiteral)
 		if !ok {
 			t.Errorf("hash key is not ast.IntegerLiteral. got=%T", key)
@@ -125,6 +131,476 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 	}
 }
 
+func TestParsingSpreadElements(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"[...a, ...b, x]", "[...a, ...b, x]"},
+		{"f(...args)", "f(...args)"},
+		{"f(1, ...tail(xs))", "f(1, ...tail(xs))"},
+		{`{...defaults}`, "{ ...defaults}"},
+		{`{"a": 1, ...defaults}`, "{ a:1, ...defaults}"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	input := `{...defaults, "fee": 2}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 2 {
+		t.Fatalf("hash exp has wrong number of pairs. want=%d, got=%d", 2, len(hash.Pairs))
+	}
+
+	spread, ok := hash.Pairs[0].Key.(*ast.SpreadElement)
+	if !ok || hash.Pairs[0].Value != nil {
+		t.Fatalf("first pair is not a spread. got=%T", hash.Pairs[0].Key)
+	}
+
+	if !testIdentifier(t, spread.Value, "defaults") { return }
+}
+
+func TestParsingRangeExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"0..10", "(0..10)"},
+		{"0..=10", "(0..=10)"},
+		{"1..n + 1", "(1..(n + 1))"},
+		{"0..10 step 2", "(0..10 step 2)"},
+		{"10..0 step -1", "(10..0 step (-1))"},
+		{"x in 0..10", "(x in (0..10))"},
+		{"let step = 2; 0..step", "let step = 2;(0..step)"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
+func TestParsingForExpression(t *testing.T) {
+	input := "for (x in xs) { puts(x) }"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	loop, ok := stmt.Expression.(*ast.ForExpression)
+	if !ok {
+		t.Fatalf("exp is not ast.ForExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, loop.Variable, "x") { return }
+
+	if !testIdentifier(t, loop.Iterable, "xs") { return }
+
+	if len(loop.Body.Statements) != 1 {
+		t.Fatalf("loop body has wrong number of statements. want=%d, got=%d", 1, len(loop.Body.Statements))
+	}
+}
+
+func TestParsingStructStatement(t *testing.T) {
+	input := `struct Account {
+		id, balance
+		fn deposit(self, amount) { self.balance + amount }
+	}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has wrong number of statements. want=%d, got=%d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.StructStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Name.Value != "Account" {
+		t.Errorf("struct has wrong name. want=%q, got=%q", "Account", stmt.Name.Value)
+	}
+
+	if len(stmt.Fields) != 2 {
+		t.Fatalf("struct has wrong number of fields. want=%d, got=%d", 2, len(stmt.Fields))
+	}
+
+	testIdentifier(t, stmt.Fields[0], "id")
+	testIdentifier(t, stmt.Fields[1], "balance")
+
+	if len(stmt.Methods) != 1 {
+		t.Fatalf("struct has wrong number of methods. want=%d, got=%d", 1, len(stmt.Methods))
+	}
+
+	method := stmt.Methods[0]
+	if method.Name.Value != "deposit" || len(method.Function.Parameters) != 2 {
+		t.Errorf("wrong method. got=%s", method.String())
+	}
+
+	if method.Function.Body.String() != "((self.balance) + amount)" {
+		t.Errorf("wrong method body. got=%q", method.Function.Body.String())
+	}
+}
+
+func TestParsingStructErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"struct A { x, x }", "duplicate member x in struct A"},
+		{"struct A { x, 1 }", "expected field or method in struct A, got INT instead"},
+		{"struct A { x", "expected next token to be }, got EOF instead"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tc.expected {
+			t.Errorf("wrong parser errors. want=%q, got=%q", tc.expected, p.Errors())
+		}
+	}
+}
+
+func TestParsingEnumStatement(t *testing.T) {
+	input := "enum Status { Pending, Settled(amount), Failed(reason, code) }"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.EnumStatement. got=%T", program.Statements[0])
+	}
+
+	expected := []string{"Pending", "Settled(amount)", "Failed(reason, code)"}
+
+	if len(stmt.Variants) != len(expected) {
+		t.Fatalf("enum has wrong number of variants. want=%d, got=%d", len(expected), len(stmt.Variants))
+	}
+
+	for i, variant := range stmt.Variants {
+		if variant.String() != expected[i] {
+			t.Errorf("wrong variant. want=%q, got=%q", expected[i], variant.String())
+		}
+	}
+}
+
+func TestParsingMatchExpression(t *testing.T) {
+	input := `match (status) {
+		Pending => 0,
+		Status.Settled(amount) => { amount }
+		_ => -1
+	}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	match, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("exp is not *ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, match.Subject, "status") { return }
+
+	expected := []string{"Pending => 0", "(Status.Settled)(amount) => amount", "_ => (-1)"}
+
+	if len(match.Arms) != len(expected) {
+		t.Fatalf("match has wrong number of arms. want=%d, got=%d", len(expected), len(match.Arms))
+	}
+
+	for i, arm := range match.Arms {
+		if arm.String() != expected[i] {
+			t.Errorf("wrong arm. want=%q, got=%q", expected[i], arm.String())
+		}
+	}
+}
+
+func TestParsingTryExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"try { f() } catch (e) { e.message }", "try { f() } catch (e) { (e.message) }"},
+		{"try { f() } catch { 0 }", "try { f() } catch { 0 }"},
+		{"try { f() } finally { g() }", "try { f() } finally { g() }"},
+		{"try { f() } catch (e) { 0 } finally { g() }", "try { f() } catch (e) { 0 } finally { g() }"},
+		{`throw "boom";`, "throw boom;"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	l := lexer.New("try { f() }")
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected catch or finally after try block" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
+
+func TestParsingPropagateExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"f(x)?", "(f(x)?)"},
+		{"f(x)? + g(y)?", "((f(x)?) + (g(y)?))"},
+		{"-f(x)?", "(-(f(x)?))"},
+		{"a.b()?", "((a.b)()?)"},
+		{"(r)?", "(r?)"},
+		{"let v = parse(s)?;", "let v = (parse(s)?);"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
+func TestParsingMemberExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"a.b", "(a.b)"},
+		{"a.b.c", "((a.b).c)"},
+		{"a.b(1)", "(a.b)(1)"},
+		{"-a.b", "(-(a.b))"},
+		{"a.b[0] + 1", "(((a.b)[0]) + 1)"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
+func TestParsingTypeAnnotations(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let x = 5;", "let x = 5;"},
+		{"fn(x: int, y: decimal) -> decimal { x }", "fn(x: int, y: decimal) -> decimal { x}"},
+		{"fn(x, y: string) { x }", "fn(x, y: string) { x}"},
+		{"fn() -> fn { x }", "fn() -> fn { x}"},
+		{"let f: fn = fn(a: Account) -> Status { a }", "let f: fn = fn(a: Account) -> Status { a};"},
+		{"10 - 1", "(10 - 1)"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
+func TestParsingYieldStatements(t *testing.T) {
+	input := "fn() { yield 1; fn(x) { x }; yield [2]; }; fn() { fn() { yield 3 } }"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	outer := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if !outer.IsGenerator {
+		t.Errorf("function yielding values is not a generator")
+	}
+
+	if program.Statements[0].String() != "fn() { yield 1;fn(x) { x}yield [2];}" {
+		t.Errorf("wrong program. got=%q", program.Statements[0].String())
+	}
+
+	inner := outer.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if inner.IsGenerator {
+		t.Errorf("function without yield is a generator")
+	}
+
+	wrapper := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if wrapper.IsGenerator {
+		t.Errorf("function wrapping a generator is a generator")
+	}
+
+	p = New(lexer.New("yield 1"))
+	p.ParseProgram()
+
+	if len(p.Errors()) != 1 || p.Errors()[0] != "yield outside of a function" {
+		t.Errorf("wrong parser errors for top level yield. got=%v", p.Errors())
+	}
+}
+
+func TestParsingConcurrencyExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"spawn f(x)", "spawn f(x)"},
+		{"spawn worker.run(1, 2)", "spawn (worker.run)(1, 2)"},
+		{"let t = spawn fn() { 1 }();", "let t = spawn fn() { 1}();"},
+		{"select { v = recv(a) => v, send(b, 1) => 2, _ => 3 }", "select { v = recv(a) => v, send(b, 1) => 2, _ => 3 }"},
+		{"select { recv(a) => { 1 } _ => { 2 } }", "select { recv(a) => 1, _ => 2 }"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	errors := []struct{
+		input    string
+		expected string
+	}{
+		{"spawn 5", "expected function call after spawn"},
+		{"select { x => 1 }", "expected recv(channel), send(channel, value) or _ in select, got x"},
+		{"select { v = send(a, 1) => 1 }", "expected recv(channel), send(channel, value) or _ in select, got send(a, 1)"},
+		{"select { recv(a, b) => 1 }", "expected recv(channel), send(channel, value) or _ in select, got recv(a, b)"},
+	}
+
+	for _, tc := range errors {
+		p := New(lexer.New(tc.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tc.expected {
+			t.Errorf("wrong parser errors for %q. want=%q, got=%v", tc.input, tc.expected, p.Errors())
+		}
+	}
+}
+
+func TestTailCallMarking(t *testing.T) {
+	tests := []struct{
+		input string
+		tail  []string
+	}{
+		{"fn(n) { f(n) }", []string{"f(n)"}},
+		{"fn(n) { f(n); g(n) }", []string{"g(n)"}},
+		{"fn(n) { if (n) { f(n) } else { g(n) } }", []string{"f(n)", "g(n)"}},
+		{"fn(n) { if (n) { return f(n); } let x = g(n); h(x) + 1 }", []string{"f(n)"}},
+		{"fn(n) { match (n) { A => f(n), _ => { g(n) } } }", []string{"f(n)", "g(n)"}},
+		{"fn(n) { for (x in n) { f(x); return g(x); } }", []string{"g(x)"}},
+		{"fn(n) { try { f(n) } catch (e) { g(e) } }", []string{}},
+		{"fn(n) { fn() { f(n) } }", []string{"f(n)"}},
+		{"f(n)", []string{}},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		tail := []string{}
+		var walk func(node ast.Node)
+		walk = func(node ast.Node) {
+			switch node := node.(type) {
+			case *ast.Program:
+				for _, s := range node.Statements { walk(s) }
+			case *ast.BlockStatement:
+				if node == nil { return }
+				for _, s := range node.Statements { walk(s) }
+			case *ast.ExpressionStatement:
+				walk(node.Expression)
+			case *ast.ReturnStatement:
+				walk(node.ReturnValue)
+			case *ast.LetStatement:
+				walk(node.Value)
+			case *ast.FunctionLiteral:
+				walk(node.Body)
+			case *ast.IfExpression:
+				walk(node.Consequence)
+				walk(node.Alternative)
+			case *ast.MatchExpression:
+				for _, arm := range node.Arms { walk(arm.Body) }
+			case *ast.ForExpression:
+				walk(node.Body)
+			case *ast.TryExpression:
+				walk(node.Block)
+				walk(node.Catch)
+			case *ast.InfixExpression:
+				walk(node.Left)
+			case *ast.CallExpression:
+				if node.IsTail { tail = append(tail, node.String()) }
+			}
+		}
+		walk(program)
+
+		if fmt.Sprint(tail) != fmt.Sprint(tc.tail) {
+			t.Errorf("wrong tail calls in %q. want=%v, got=%v", tc.input, tc.tail, tail)
+		}
+	}
+}
+
 func TestParsingEmptyHashLiteral(t *testing.T) {
 	input := "{}"
 
@@ -168,7 +644,13 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
+	if hash.String() != "{ one:1, two:2, three:3}" {
+		t.Errorf("hash literal lost the order of its pairs. got=%s", hash.String())
+	}
+
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
+
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -413,6 +895,21 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	p := New(lexer.New("99999999999999999999"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	literal, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("expression not *ast.IntegerLiteral. got=%T instead", program.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+
+	if literal.Big == nil || literal.Big.String() != "99999999999999999999" {
+		t.Errorf("literal.Big not %s. got=%v instead", "99999999999999999999", literal.Big)
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct{
 		input        string
@@ -590,6 +1087,7 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"3 + 4; -5 * 5", "(3 + 4)((-5) * 5)"},
 		{"5 > 4 == 3 < 4", "((5 > 4) == (3 < 4))"},
 		{"5 < 4 != 3 > 4", "((5 < 4) != (3 > 4))"},
+		{"a + 1 <= b == c >= d * 2", "(((a + 1) <= b) == (c >= (d * 2)))"},
 		{"3 + 4 * 5 == 3 * 1 + 4 * 5", "((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))"},
 		{"true", "true"},
 		{"false", "false"},
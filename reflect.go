@@ -0,0 +1,313 @@
+package dux
+
+import (
+	"dux/evaluator"
+	"dux/object"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+/*
+	Binding of arbitrary Go values through reflection: functions become
+	builtins converting their arguments and results, and structs become
+	objects whose exported fields can be read and whose methods can be called
+	(i.e. point.X, point.Move(1, 2)).
+*/
+
+var (
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+	anyType    = reflect.TypeOf((*any)(nil)).Elem()
+)
+
+/*
+	Wraps fn, which must be a Go func, into a builtin. Arguments are converted
+	to the types of its parameters and its results back to dx values: none is
+	nil, one is that value and several are an array. A trailing error result
+	that isn't nil fails the call with a RuntimeError instead, and so does a
+	panic, which dx code can catch rather than it crashing the host.
+*/
+func Func(fn any) (*object.Builtin, error) {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		return nil, fmt.Errorf("cannot bind %T as a function", fn)
+	}
+
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return call(value, args)
+	}}, nil
+}
+
+func call(fn reflect.Value, args []object.Object) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil { result = runtimeError("RuntimeError", "go_panic", "panic: %v", r) }
+	}()
+
+	fnType := fn.Type()
+
+	want := fnType.NumIn()
+	if fnType.IsVariadic() {
+		if len(args) < want-1 { return runtimeError("ArityError", "wrong_arguments", "wrong number of arguments. got=%d, want at least %d", len(args), want-1) }
+	} else if len(args) != want {
+		return runtimeError("ArityError", "wrong_arguments", "wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		paramType := paramType(fnType, i)
+
+		value, err := toValue(arg, paramType)
+		if err != nil { return runtimeError("TypeError", "invalid_argument", "invalid argument %d: %s", i+1, err) }
+
+		in[i] = value
+	}
+
+	out := fn.Call(in)
+
+	if n := len(out); n > 0 && fnType.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil { return runtimeError("RuntimeError", "go_error", "%s", err) }
+		out = out[:n-1]
+	}
+
+	results := make([]object.Object, len(out))
+	for i, value := range out {
+		obj, err := fromValue(value)
+		if err != nil { return runtimeError("TypeError", "invalid_result", "invalid result %d: %s", i+1, err) }
+
+		results[i] = obj
+	}
+
+	switch len(results) {
+	case 0:
+		return evaluator.NIL
+	case 1:
+		return results[0]
+	default:
+		return &object.Array{Elements: results}
+	}
+}
+
+// Returns the type of the ith argument to a function of type fnType, variadic ones included.
+func paramType(fnType reflect.Type, i int) reflect.Type {
+	if fnType.IsVariadic() && i >= fnType.NumIn()-1 {
+		return fnType.In(fnType.NumIn() - 1).Elem()
+	}
+
+	return fnType.In(i)
+}
+
+func runtimeError(kind, code string, format string, a ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: kind, Code: code}
+}
+
+/*
+	A GoObject is a Go struct exposed to dx, through a pointer to it so that
+	methods with pointer receivers can be called too. Its type is the name of
+	the struct's Go type.
+*/
+type GoObject struct {
+	Value reflect.Value
+}
+
+func (g *GoObject) Type() object.ObjectType { return object.ObjectType(g.Value.Elem().Type().Name()) }
+func (g *GoObject) Inspect() string        { return fmt.Sprintf("%+v", g.Value.Elem().Interface()) }
+
+// Returns the exported field or method named name.
+func (g *GoObject) Member(name string) (object.Object, bool) {
+	if field, ok := g.Value.Elem().Type().FieldByName(name); ok && field.IsExported() {
+		obj, err := fromValue(g.Value.Elem().FieldByIndex(field.Index))
+		if err != nil { return runtimeError("TypeError", "invalid_result", "invalid field %s: %s", name, err), true }
+
+		return obj, true
+	}
+
+	if method := g.Value.MethodByName(name); method.IsValid() {
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return call(method, args)
+		}}, true
+	}
+
+	return nil, false
+}
+
+/*
+	Converts a Go value to a dx object: integers, strings and bools to their dx
+	counterparts, *big.Int to integers of any size, slices and arrays to
+	arrays, maps keyed by strings to hashes (with their keys sorted), functions
+	to builtins (see Func) and structs to a *GoObject.
+*/
+func fromValue(value reflect.Value) (object.Object, error) {
+	if !value.IsValid() { return evaluator.NIL, nil }
+
+	if value.CanInterface() {
+		if obj, ok := value.Interface().(object.Object); ok { return obj, nil }
+	}
+
+	if value.Type() == bigIntType {
+		if value.IsNil() { return evaluator.NIL, nil }
+
+		return object.IntegerOf(new(big.Int).Set(value.Interface().(*big.Int))), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: value.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.Uint() > 1<<63-1 { return nil, fmt.Errorf("%d overflows INTEGER", value.Uint()) }
+
+		return &object.Integer{Value: int64(value.Uint())}, nil
+	case reflect.String:
+		return &object.String{Value: value.String()}, nil
+	case reflect.Bool:
+		return ToObject(value.Bool())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() { return evaluator.NIL, nil }
+
+		elements := make([]object.Object, value.Len())
+		for i := range elements {
+			element, err := fromValue(value.Index(i))
+			if err != nil { return nil, err }
+
+			elements[i] = element
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String { return nil, fmt.Errorf("cannot convert %s to a dx value", value.Type()) }
+		if value.IsNil() { return evaluator.NIL, nil }
+
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		hash := object.NewHash(len(keys))
+		for _, key := range keys {
+			element, err := fromValue(value.MapIndex(key))
+			if err != nil { return nil, err }
+
+			hash.Set(&object.String{Value: key.String()}, element)
+		}
+		return hash, nil
+	case reflect.Func:
+		if value.IsNil() { return evaluator.NIL, nil }
+
+		return Func(value.Interface())
+	case reflect.Interface:
+		if value.IsNil() { return evaluator.NIL, nil }
+
+		return fromValue(value.Elem())
+	case reflect.Pointer:
+		if value.IsNil() { return evaluator.NIL, nil }
+		if value.Elem().Kind() != reflect.Struct { return fromValue(value.Elem()) }
+
+		return &GoObject{Value: value}, nil
+	case reflect.Struct:
+		pointer := reflect.New(value.Type())
+		pointer.Elem().Set(value)
+
+		return &GoObject{Value: pointer}, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %s to a dx value", value.Type())
+	}
+}
+
+// Converts a dx object to a Go value of type to, the other way around from fromValue.
+func toValue(obj object.Object, to reflect.Type) (reflect.Value, error) {
+	if to.Kind() != reflect.Interface && reflect.TypeOf(obj).AssignableTo(to) { return reflect.ValueOf(obj), nil }
+
+	if goObj, ok := obj.(*GoObject); ok {
+		switch {
+		case goObj.Value.Type().AssignableTo(to):
+			return goObj.Value, nil
+		case goObj.Value.Elem().Type().AssignableTo(to):
+			return goObj.Value.Elem(), nil
+		}
+	}
+
+	if _, ok := obj.(*object.Nil); ok {
+		switch to.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(to), nil
+		}
+	}
+
+	if to == bigIntType {
+		if value, ok := object.BigValue(obj); ok { return reflect.ValueOf(new(big.Int).Set(value)), nil }
+	}
+
+	if integer, ok := obj.(*object.BigInteger); ok {
+		switch to.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return reflect.Value{}, fmt.Errorf("%s overflows %s", integer.Inspect(), to)
+		}
+	}
+
+	switch to.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer, ok := obj.(*object.Integer)
+		if !ok { break }
+
+		value := reflect.New(to).Elem()
+		if value.OverflowInt(integer.Value) { return value, fmt.Errorf("%d overflows %s", integer.Value, to) }
+
+		value.SetInt(integer.Value)
+		return value, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		integer, ok := obj.(*object.Integer)
+		if !ok { break }
+
+		value := reflect.New(to).Elem()
+		if integer.Value < 0 || value.OverflowUint(uint64(integer.Value)) {
+			return value, fmt.Errorf("%d overflows %s", integer.Value, to)
+		}
+
+		value.SetUint(uint64(integer.Value))
+		return value, nil
+	case reflect.String:
+		str, ok := obj.(*object.String)
+		if !ok { break }
+
+		return reflect.ValueOf(str.Value).Convert(to), nil
+	case reflect.Bool:
+		boolean, ok := obj.(*object.Boolean)
+		if !ok { break }
+
+		return reflect.ValueOf(boolean.Value).Convert(to), nil
+	case reflect.Slice:
+		array, ok := obj.(*object.Array)
+		if !ok { break }
+
+		value := reflect.MakeSlice(to, len(array.Elements), len(array.Elements))
+		for i, element := range array.Elements {
+			converted, err := toValue(element, to.Elem())
+			if err != nil { return value, err }
+
+			value.Index(i).Set(converted)
+		}
+		return value, nil
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok || to.Key().Kind() != reflect.String { break }
+
+		value := reflect.MakeMapWithSize(to, hash.Len())
+		for _, pair := range hash.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok { return value, fmt.Errorf("cannot use %s key in %s", pair.Key.Type(), to) }
+
+			converted, err := toValue(pair.Value, to.Elem())
+			if err != nil { return value, err }
+
+			value.SetMapIndex(reflect.ValueOf(key.Value).Convert(to.Key()), converted)
+		}
+		return value, nil
+	case reflect.Interface:
+		// Native values first, so that any parameters get Go values
+		native := ToNative(obj)
+		if native == nil { return reflect.Zero(to), nil }
+		if reflect.TypeOf(native).AssignableTo(to) { return reflect.ValueOf(native), nil }
+		if reflect.TypeOf(obj).AssignableTo(to) { return reflect.ValueOf(obj), nil }
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), to)
+}
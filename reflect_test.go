@@ -0,0 +1,131 @@
+package dux
+
+import (
+	"dux/object"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X, Y   int
+	hidden string
+}
+
+func (p *point) Move(dx, dy int) *point { return &point{X: p.X + dx, Y: p.Y + dy} }
+func (p point) String() string         { return fmt.Sprintf("(%d, %d)", p.X, p.Y) }
+
+func TestBindFunctions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"add(1, 2)", int64(3)},
+		{`repeat("ab", 2)`, "abab"},
+		{"sum(1, 2, 3)", int64(6)},
+		{"sum()", int64(0)},
+		{`join(["a", "b"], "-")`, "a-b"},
+		{`keys({"a": 1})`, []any{"a"}},
+		{"divide(7, 2)", []any{int64(3), int64(1)}},
+		{"describe(1)", "int64"},
+		{"describe([1])", "[]interface {}"},
+		{"noop()", nil},
+		{"parse(\"12\")", int64(12)},
+		{"try { parse(\"x\") } catch (e) { e.message }", "not a number: x"},
+		{"add(1)", "wrong number of arguments. got=1, want=2"},
+		{`add(1, "2")`, "invalid argument 2: cannot use STRING as int"},
+		{"small(300)", "invalid argument 1: 300 overflows int8"},
+		{"at([1, 2], 5)", "panic: runtime error: index out of range [5] with length 2"},
+		{"try { at([1, 2], 5) } catch (e) { e.code }", "go_panic"},
+	}
+
+	for _, tc := range tests {
+		interp := New()
+		globals := map[string]any{
+			"add":      func(a, b int) int { return a + b },
+			"repeat":   strings.Repeat,
+			"sum":      func(values ...int64) int64 { total := int64(0); for _, v := range values { total += v }; return total },
+			"join":     strings.Join,
+			"keys":     func(m map[string]int) []string { keys := []string{}; for k := range m { keys = append(keys, k) }; return keys },
+			"divide":   func(a, b int) (int, int) { return a / b, a % b },
+			"describe": func(v any) string { return fmt.Sprintf("%T", v) },
+			"noop":     func() {},
+			"parse":    parse,
+			"small":    func(n int8) int8 { return n },
+			"at":       func(xs []int, n int) int { return xs[n] },
+		}
+		for name, fn := range globals {
+			if err := interp.SetGlobal(name, fn); err != nil { t.Fatalf("unexpected error binding %s: %s", name, err) }
+		}
+
+		result, err := run(interp, tc.input)
+		if err != nil {
+			var dxErr *Error
+			if errors.As(err, &dxErr) { result = dxErr.Message }
+		}
+
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("wrong result for %q. want=%#v, got=%#v", tc.input, tc.expected, result)
+		}
+	}
+}
+
+func parse(s string) (int, error) {
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' { return 0, fmt.Errorf("not a number: %s", s) }
+		n = n*10 + int(c-'0')
+	}
+
+	return n, nil
+}
+
+func TestBindStructs(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"p.X + p.Y", int64(3)},
+		{"p.Move(10, 20).Y", int64(22)},
+		{"p.String()", "(1, 2)"},
+		{"type(p)", "point"},
+		{"distance(p, p.Move(3, 4))", int64(7)},
+		{"p.hidden", "unknown member hidden on point"},
+		{"p.Missing", "unknown member Missing on point"},
+	}
+
+	for _, tc := range tests {
+		interp := New()
+		interp.SetGlobal("p", point{X: 1, Y: 2, hidden: "secret"})
+		interp.SetGlobal("distance", func(a, b *point) int { return (b.X - a.X) + (b.Y - a.Y) })
+
+		result, err := run(interp, tc.input)
+		if err != nil {
+			var dxErr *Error
+			if errors.As(err, &dxErr) { result = dxErr.Message }
+		}
+
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("wrong result for %q. want=%#v, got=%#v", tc.input, tc.expected, result)
+		}
+	}
+
+	if _, err := Func(42); err == nil {
+		t.Errorf("expected an error binding a non function")
+	}
+}
+
+func TestPanicConvertingArguments(t *testing.T) {
+	builtin, err := Func(func(p *point) int { return p.X })
+	if err != nil { t.Fatalf("unexpected error binding a function: %s", err) }
+
+	// A GoObject made by hand around a value rather than a pointer panics once converted
+	result := builtin.Fn(&GoObject{Value: reflect.ValueOf(3)})
+
+	dxErr, ok := result.(*object.Error)
+	if !ok || dxErr.Code != "go_panic" {
+		t.Errorf("expected a go_panic error, got %#v", result)
+	}
+}
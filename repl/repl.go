@@ -5,8 +5,8 @@ import (
 	"dux/evaluator"
 	"dux/lexer"
 	"dux/object"
+	"dux/optimizer"
 	"dux/parser"
-	"fmt"
 	"io"
 )
 
@@ -15,9 +15,10 @@ const ARROW = ">> "
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	env.SetRuntime(&object.Runtime{Stdout: out})
 
 	for {
-		fmt.Printf(ARROW)
+		io.WriteString(out, ARROW)
 		scanned := scanner.Scan()
 
 		if !scanned { return }
@@ -25,12 +26,23 @@ func Start(in io.Reader, out io.Writer) {
 		line := scanner.Text()
 		l := lexer.New(line)
 		p := parser.New(l)
-		program := p.ParseProgram()
+		program := optimizer.Optimize(p.ParseProgram())
+
+		if errors := evaluator.Resolve(program, env); len(errors) != 0 {
+			for _, err := range errors {
+				io.WriteString(out, "\t"+err.Error()+"\n")
+			}
+			continue
+		}
 
 		evaluated := evaluator.Eval(program, env)
 
 		if evaluated == nil { continue }
-		
+
+		if err, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, err.Traceback())
+		}
+
 		io.WriteString(out, evaluated.Inspect())
 		io.WriteString(out, "\n")
 	}
@@ -0,0 +1,16 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	var out strings.Builder
+	Start(strings.NewReader("let x = 2\nputs(x * 3)\ny\n"), &out)
+
+	expected := ">> >> 6\nnil\n>> \t1:1: identifier not found: y\n>> "
+	if out.String() != expected {
+		t.Errorf("wrong output. want=%q, got=%q", expected, out.String())
+	}
+}
@@ -0,0 +1,323 @@
+package resolver
+
+import (
+	"dux/ast"
+	"dux/token"
+	"fmt"
+)
+
+/*
+	The resolver is a static pass binding every identifier of an *ast.Program to
+	the scope that declares it, as a (depth, slot) pair, before the program runs.
+	The evaluator then reaches a variable by hopping depth environments out and
+	indexing its slots, rather than hashing its name in every environment on
+	the way.
+
+	Scopes are those the evaluator creates environments for: function calls,
+	for-in iterations, match arms, catch blocks and select cases. Variables of
+	the program's own scope stay bound by name, so that environments can be
+	shared between programs (i.e. the lines of the REPL).
+
+	Names that are declared nowhere, and aren't defined by the environment the
+	program runs in either, are reported as errors.
+*/
+
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message) }
+
+type variable struct {
+	slot    int
+	defined bool // Cleared while the variable is declared ahead of its binding
+}
+
+type scope struct {
+	variables map[string]*variable
+	function  bool // Set on the scope of a function body
+	outer     *scope
+}
+
+type resolver struct {
+	scope   *scope // nil in the program's scope
+	globals map[string]bool
+	free    []*ast.Identifier // Names read from the program's scope
+	errors  []*Error
+}
+
+/*
+	Resolves the identifiers of program in place. defined reports the names the
+	environment the program runs in already binds, builtins included. A nil
+	defined leaves names declared nowhere to fail when they're reached instead.
+*/
+func Resolve(program *ast.Program, defined func(name string) bool) []*Error {
+	r := &resolver{globals: map[string]bool{}}
+
+	for _, stmt := range program.Statements {
+		r.resolve(stmt)
+	}
+
+	if defined == nil { return r.errors }
+
+	// Read once the whole program is known, since functions may read globals bound after them
+	for _, ident := range r.free {
+		if !r.globals[ident.Value] && !defined(ident.Value) {
+			r.errorf(ident.Token, "identifier not found: %s", ident.Value)
+		}
+	}
+
+	return r.errors
+}
+
+func (r *resolver) errorf(tok token.Token, format string, a ...interface{}) {
+	r.errors = append(r.errors, &Error{Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *resolver) pushScope(function bool) {
+	r.scope = &scope{variables: map[string]*variable{}, function: function, outer: r.scope}
+}
+
+func (r *resolver) popScope() { r.scope = r.scope.outer }
+
+/*
+	Declares the name ident binds in the current scope, reusing its slot if it
+	was declared already. Undefined declarations are only visible from nested
+	functions, which can't run before the binding is made.
+*/
+func (r *resolver) declare(ident *ast.Identifier, defined bool) {
+	if r.scope == nil {
+		r.globals[ident.Value] = true
+		ident.Binding = &ast.Binding{Slot: ast.Global}
+		return
+	}
+
+	v, ok := r.scope.variables[ident.Value]
+	if !ok {
+		v = &variable{slot: len(r.scope.variables)}
+		r.scope.variables[ident.Value] = v
+	}
+
+	v.defined = v.defined || defined
+	ident.Binding = &ast.Binding{Slot: v.slot}
+}
+
+/*
+	Binds ident to the innermost scope declaring it, or to the program's scope.
+	Free names are checked against the globals unless lenient, which is how
+	patterns naming enum variants are resolved.
+*/
+func (r *resolver) lookup(ident *ast.Identifier, lenient bool) {
+	if ident.TokenLiteral() == "nil" { return }
+
+	depth := 0
+	crossed := false
+
+	for s := r.scope; s != nil; s = s.outer {
+		if v, ok := s.variables[ident.Value]; ok && (v.defined || crossed) {
+			ident.Binding = &ast.Binding{Depth: depth, Slot: v.slot}
+			return
+		}
+
+		crossed = crossed || s.function
+		depth++
+	}
+
+	ident.Binding = &ast.Binding{Depth: depth, Slot: ast.Global}
+
+	if !lenient {
+		r.free = append(r.free, ident)
+	}
+}
+
+/*
+	Declares ahead the names bound by statements, including those in nested
+	if and try blocks which bind in the same scope, so that functions can
+	refer to variables bound after them (i.e. mutually recursive closures).
+*/
+func (r *resolver) hoist(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			r.declare(stmt.Name, false)
+			r.hoistExpression(stmt.Value)
+		case *ast.StructStatement:
+			r.declare(stmt.Name, false)
+		case *ast.EnumStatement:
+			r.declare(stmt.Name, false)
+		case *ast.ExpressionStatement:
+			r.hoistExpression(stmt.Expression)
+		}
+	}
+}
+
+func (r *resolver) hoistExpression(expression ast.Expression) {
+	switch node := expression.(type) {
+	case *ast.IfExpression:
+		r.hoistBlock(node.Consequence)
+		r.hoistBlock(node.Alternative)
+	case *ast.TryExpression:
+		r.hoistBlock(node.Block)
+		r.hoistBlock(node.Finally)
+	}
+}
+
+func (r *resolver) hoistBlock(block *ast.BlockStatement) {
+	if block != nil {
+		r.hoist(block.Statements)
+	}
+}
+
+// Resolves a block in a new scope, where bindings are made by bind.
+func (r *resolver) resolveScope(block *ast.BlockStatement, function bool, bind func()) {
+	r.pushScope(function)
+	bind()
+	r.hoistBlock(block)
+	r.resolve(block)
+	r.popScope()
+}
+
+func (r *resolver) resolve(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		r.resolve(node.Expression)
+	case *ast.LetStatement:
+		r.resolve(node.Value)
+		r.declare(node.Name, true)
+	case *ast.ReturnStatement:
+		r.resolve(node.ReturnValue)
+	case *ast.ThrowStatement:
+		r.resolve(node.Value)
+	case *ast.YieldStatement:
+		r.resolve(node.Value)
+	case *ast.BlockStatement:
+		if node == nil { return }
+
+		for _, stmt := range node.Statements {
+			r.resolve(stmt)
+		}
+	case *ast.Identifier:
+		r.lookup(node, false)
+	case *ast.PrefixExpression:
+		r.resolve(node.Right)
+	case *ast.InfixExpression:
+		r.resolve(node.Left)
+		r.resolve(node.Right)
+	case *ast.IfExpression:
+		r.resolve(node.Condition)
+		r.resolve(node.Consequence)
+		r.resolve(node.Alternative)
+	case *ast.FunctionLiteral:
+		r.resolveFunction(node)
+	case *ast.CallExpression:
+		r.resolve(node.Function)
+		r.resolveAll(node.Arguments)
+	case *ast.ArrayLiteral:
+		r.resolveAll(node.Elements)
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			r.resolve(pair.Key)
+			if pair.Value != nil { r.resolve(pair.Value) }
+		}
+	case *ast.IndexExpresssion:
+		r.resolve(node.Left)
+		r.resolve(node.Index)
+	case *ast.MemberExpression:
+		r.resolve(node.Left)
+	case *ast.SpreadElement:
+		r.resolve(node.Value)
+	case *ast.PropagateExpression:
+		r.resolve(node.Value)
+	case *ast.RangeExpression:
+		r.resolve(node.Start)
+		r.resolve(node.End)
+		r.resolve(node.Step)
+	case *ast.ForExpression:
+		r.resolve(node.Iterable)
+		r.resolveScope(node.Body, false, func() { r.declare(node.Variable, true) })
+	case *ast.StructStatement:
+		for _, method := range node.Methods {
+			r.resolveFunction(method.Function)
+		}
+		r.declare(node.Name, true)
+	case *ast.EnumStatement:
+		r.declare(node.Name, true)
+	case *ast.MatchExpression:
+		r.resolve(node.Subject)
+
+		for _, arm := range node.Arms {
+			r.resolveScope(arm.Body, false, func() { r.resolvePattern(arm.Pattern) })
+		}
+	case *ast.TryExpression:
+		r.resolve(node.Block)
+
+		if node.Catch != nil {
+			r.resolveScope(node.Catch, false, func() {
+				if node.CatchParam != nil { r.declare(node.CatchParam, true) }
+			})
+		}
+
+		r.resolve(node.Finally)
+	case *ast.SpawnExpression:
+		r.resolve(node.Call)
+	case *ast.SelectExpression:
+		for _, selectCase := range node.Cases {
+			if selectCase.Operation != nil {
+				r.resolveAll(selectCase.Operation.Arguments)
+			}
+
+			r.resolveScope(selectCase.Body, false, func() {
+				if selectCase.Binding != nil { r.declare(selectCase.Binding, true) }
+			})
+		}
+	}
+}
+
+func (r *resolver) resolveAll(expressions []ast.Expression) {
+	for _, expression := range expressions {
+		r.resolve(expression)
+	}
+}
+
+func (r *resolver) resolveFunction(fn *ast.FunctionLiteral) {
+	r.resolveScope(fn.Body, true, func() {
+		for _, param := range fn.Parameters {
+			r.declare(param, true)
+		}
+	})
+}
+
+/*
+	Resolves a match arm pattern: a bare name may be a variant of the subject's
+	enum rather than a variable, and the names in Variant(a, b) are bindings.
+*/
+func (r *resolver) resolvePattern(pattern ast.Expression) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			r.lookup(pattern, true)
+		}
+	case *ast.CallExpression:
+		if head, ok := pattern.Function.(*ast.Identifier); ok {
+			r.lookup(head, true)
+		} else {
+			r.resolve(pattern.Function)
+		}
+
+		for _, arg := range pattern.Arguments {
+			binding, ok := arg.(*ast.Identifier)
+			if !ok {
+				r.resolve(arg)
+				continue
+			}
+
+			if binding.Value != "_" {
+				r.declare(binding, true)
+			}
+		}
+	default:
+		r.resolve(pattern)
+	}
+}
@@ -0,0 +1,189 @@
+package resolver_test
+
+import (
+	"dux/ast"
+	"dux/evaluator"
+	"dux/lexer"
+	"dux/object"
+	"dux/parser"
+	"dux/resolver"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestResolveBindings(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let x = 1; x", "x=0:global x=0:global"},
+		{"fn(a, b) { a + b }", "a=0:0 b=0:1 a=0:0 b=0:1"},
+		{"fn(a) { fn(b) { a + b } }", "a=0:0 b=0:0 a=1:0 b=0:0"},
+		{"fn(a) { let b = a; b }", "a=0:0 b=0:1 a=0:0 b=0:1"},
+		{"fn(a) { for (i in a) { i + a } }", "a=0:0 i=0:0 a=0:0 i=0:0 a=1:0"},
+		{"fn() { x }", "x=1:global"},
+		{"fn(len) { len }", "len=0:0 len=0:0"},
+		{"fn() { let x = x; x }", "x=0:0 x=1:global x=0:0"},
+		{"fn() { let f = fn() { f }; f }", "f=0:0 f=1:0 f=0:0"},
+		{"fn() { let g = fn() { h() }; let h = fn() { 1 }; g }", "g=0:0 h=1:1 h=0:1 g=0:0"},
+		{"fn() { if (true) { let y = 1 }; y }", "y=0:0 y=0:0"},
+		{"fn() { try { 1 } catch (e) { e } }", "e=0:0 e=0:0"},
+		{"fn(r) { match (r) { Ok(v) => v, Other => 1 } }", "r=0:0 r=0:0 Ok=2:global v=0:0 v=0:0 Other=2:global"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Set("x", &object.Integer{Value: 1})
+
+		errors := evaluator.Resolve(program, env)
+
+		if len(errors) != 0 {
+			t.Errorf("unexpected errors for %q: %v", tc.input, errors)
+			continue
+		}
+
+		if got := bindings(program); got != tc.expected {
+			t.Errorf("wrong bindings for %q. want=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		{"x", []string{"1:1: identifier not found: x"}},
+		{"let f = fn() { g() }; f()", []string{"1:16: identifier not found: g"}},
+		{"let f = fn() { g() }; let g = fn() { 1 }; f()", []string{}},
+		{"let f = fn() { let y = 1 }; y + z", []string{"1:29: identifier not found: y", "1:33: identifier not found: z"}},
+		{"match (1) { Unknown => 1 }", []string{}},
+		{"puts(defined)", []string{}},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Set("defined", &object.Integer{Value: 1})
+
+		errors := evaluator.Resolve(program, env)
+
+		if len(errors) != len(tc.expected) {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%v", tc.input, len(tc.expected), errors)
+			continue
+		}
+
+		for i, err := range errors {
+			if err.Error() != tc.expected[i] {
+				t.Errorf("wrong error for %q. want=%q, got=%q", tc.input, tc.expected[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestResolveWithoutEnvironment(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn(x) { x + y }; f(1)")).ParseProgram()
+	if errors := resolver.Resolve(program, nil); len(errors) != 0 {
+		t.Fatalf("names declared nowhere must not be reported. got=%v", errors)
+	}
+
+	if got := bindings(program); got != "f=0:global x=0:0 x=0:0 y=1:global f=0:global" {
+		t.Errorf("wrong bindings. got=%q", got)
+	}
+}
+
+// Renders the bindings of the identifiers in node, in source order.
+func bindings(node ast.Node) string {
+	out := []string{}
+	var visit func(node ast.Node)
+
+	visit = func(node ast.Node) {
+		switch node := node.(type) {
+		case *ast.Program:
+			for _, stmt := range node.Statements { visit(stmt) }
+		case *ast.BlockStatement:
+			for _, stmt := range node.Statements { visit(stmt) }
+		case *ast.ExpressionStatement:
+			visit(node.Expression)
+		case *ast.LetStatement:
+			visit(node.Name)
+			visit(node.Value)
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters { visit(param) }
+			visit(node.Body)
+		case *ast.IfExpression:
+			visit(node.Condition)
+			visit(node.Consequence)
+		case *ast.InfixExpression:
+			visit(node.Left)
+			visit(node.Right)
+		case *ast.CallExpression:
+			visit(node.Function)
+			for _, arg := range node.Arguments { visit(arg) }
+		case *ast.ForExpression:
+			visit(node.Variable)
+			visit(node.Iterable)
+			visit(node.Body)
+		case *ast.TryExpression:
+			visit(node.Block)
+			visit(node.CatchParam)
+			visit(node.Catch)
+		case *ast.MatchExpression:
+			visit(node.Subject)
+			for _, arm := range node.Arms {
+				visit(arm.Pattern)
+				visit(arm.Body)
+			}
+		case *ast.Identifier:
+			if node == nil || node.Binding == nil { return }
+
+			slot := fmt.Sprint(node.Binding.Slot)
+			if node.Binding.Slot == ast.Global { slot = "global" }
+
+			out = append(out, fmt.Sprintf("%s=%d:%s", node.Value, node.Binding.Depth, slot))
+		}
+	}
+
+	visit(node)
+
+	return strings.Join(out, " ")
+}
+
+/*
+	The same programs evaluated with and without resolving them first, the
+	latter looking every variable up by name.
+*/
+const fibonacci = `
+	let fibonacci = fn(n) { if (n < 2) { n } else { fibonacci(n - 1) + fibonacci(n - 2) } };
+	fibonacci(20)
+`
+
+const closures = `
+	let outer = fn(a) {
+		let b = a + 1;
+		fn(c) {
+			let d = b + c;
+			let loop = fn(n, acc) { if (n == 0) { acc } else { loop(n - 1, acc + a + b + c + d) } };
+			loop(20000, 0)
+		}
+	};
+	outer(1)(2)
+`
+
+func BenchmarkFibonacciByName(b *testing.B) { benchmarkEval(b, fibonacci, false) }
+func BenchmarkFibonacciResolved(b *testing.B) { benchmarkEval(b, fibonacci, true) }
+func BenchmarkClosuresByName(b *testing.B) { benchmarkEval(b, closures, false) }
+func BenchmarkClosuresResolved(b *testing.B) { benchmarkEval(b, closures, true) }
+
+func benchmarkEval(b *testing.B, input string, resolve bool) {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	if resolve {
+		evaluator.Resolve(program, object.NewEnvironment())
+	}
+
+	for i := 0; i < b.N; i++ {
+		evaluator.Eval(program, object.NewEnvironment())
+	}
+}
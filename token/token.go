@@ -19,15 +19,26 @@ const (
 	EXCLAMATION = "!"
 	STHAN       = "<"
 	GTHAN       = ">"
+	QUESTION    = "?"
 
 	// Double Operators
 	EQUAL  = "=="
 	NEQUAL = "!="
+	STHANEQ = "<="
+	GTHANEQ = ">="
+	DOTDOT = ".."
+	FATARROW = "=>"
+	ARROW = "->"
+
+	// Triple Operators
+	ELLIPSIS = "..."
+	DOTDOTEQ = "..="
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	DOT       = "."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -44,6 +55,18 @@ const (
 	RETURN = "RETURN"
 	TRUE = "TRUE"
 	FALSE = "FALSE"
+	FOR = "FOR"
+	IN = "IN"
+	STRUCT = "STRUCT"
+	ENUM = "ENUM"
+	MATCH = "MATCH"
+	TRY = "TRY"
+	CATCH = "CATCH"
+	FINALLY = "FINALLY"
+	THROW = "THROW"
+	YIELD = "YIELD"
+	SPAWN = "SPAWN"
+	SELECT = "SELECT"
 
 	// Records
 	STRING = "STRING"
@@ -52,6 +75,8 @@ const (
 type Token struct {
 	Type TokenType
 	Literal string
+	Line int // Line of the token's first character, starting at 1
+	Column int // Column of the token's first character, starting at 1
 }
 
 var keywords = map[string]TokenType {
@@ -62,6 +87,18 @@ var keywords = map[string]TokenType {
 	"return": RETURN,
 	"true": TRUE,
 	"false": FALSE,
+	"for": FOR,
+	"in": IN,
+	"struct": STRUCT,
+	"enum": ENUM,
+	"match": MATCH,
+	"try": TRY,
+	"catch": CATCH,
+	"finally": FINALLY,
+	"throw": THROW,
+	"yield": YIELD,
+	"spawn": SPAWN,
+	"select": SELECT,
 }
 
 func LookupType(ident string) TokenType {
@@ -0,0 +1,393 @@
+package vm
+
+import (
+	"context"
+	"dux/ast"
+	"dux/compiler"
+	"dux/evaluator"
+	"dux/object"
+	"dux/token"
+)
+
+/*
+	The vm is a stack machine running the bytecode of package compiler. Calls
+	to dx functions push a frame instead of recursing, tail calls replace the
+	calling frame, and the nodes the compiler left to the evaluator are
+	evaluated in the frame's environment.
+
+	Programs run in an environment with a sandbox are bounded by it the same
+	way evaluated ones are: every instruction is a step, every frame a call in
+	progress, and arrays, strings and builtin calls are charged for what they
+	allocate (see RunContext).
+
+	Function bodies are compiled the first time they're called, whatever
+	engine created the function, and the bytecode is kept with the program's
+	for every later call (see compiler.Bytecode.Function).
+*/
+type VM struct {
+	stack  []object.Object
+	frames []frame
+}
+
+type frame struct {
+	code     *compiler.Bytecode
+	ip       int
+	env      *object.Environment
+	base     int              // Height of the stack when the frame was called
+	function *object.Function // Function the frame runs, nil for the program
+	site     token.Token      // Position of the call
+	sandbox  *object.Sandbox  // Sandbox the call was entered in, nil for the program
+}
+
+func New() *VM {
+	return &VM{stack: make([]object.Object, 0, 256)}
+}
+
+// Runs bytecode in env, returning what the program evaluates to.
+func Run(bytecode *compiler.Bytecode, env *object.Environment) object.Object {
+	return New().Run(bytecode, env)
+}
+
+/*
+	Runs bytecode in env within limits, until ctx is done, the way
+	evaluator.EvalContext evaluates a program: on a goroutine of its own,
+	failing with an error of the LimitError kind once a limit is hit.
+*/
+func RunContext(ctx context.Context, bytecode *compiler.Bytecode, env *object.Environment, limits evaluator.Limits) object.Object {
+	sandbox := object.NewSandbox(ctx, limits.MaxSteps, limits.MaxDepth, limits.MaxMemory)
+	sandboxed := object.NewSandboxedEnvironment(env, sandbox)
+
+	done := make(chan object.Object, 1)
+	go func() { done <- New().Run(bytecode, sandboxed) }()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return sandbox.Cancel()
+	}
+}
+
+func (vm *VM) Run(bytecode *compiler.Bytecode, env *object.Environment) object.Object {
+	vm.stack = vm.stack[:0]
+	vm.frames = append(vm.frames[:0], frame{code: bytecode, env: env})
+
+	return vm.run()
+}
+
+func (vm *VM) push(obj object.Object) { vm.stack = append(vm.stack, obj) }
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+
+	return obj
+}
+
+func (vm *VM) run() object.Object {
+	for {
+		f := &vm.frames[len(vm.frames)-1]
+		ins := f.code.Instructions
+		start := f.ip
+		op := compiler.Opcode(ins[start])
+
+		sandbox := f.env.Sandbox()
+		if sandbox != nil {
+			if err := sandbox.Step(); err != nil { return vm.unwind(evaluator.WithPosition(err, f.code.Positions[start])) }
+		}
+
+		f.ip++
+
+		var result object.Object
+
+		switch op {
+		case compiler.OpConstant:
+			vm.push(f.code.Constants[vm.operand(f)])
+			continue
+		case compiler.OpNone:
+			vm.push(nil)
+			continue
+		case compiler.OpNil:
+			vm.push(evaluator.NIL)
+			continue
+		case compiler.OpTrue:
+			vm.push(evaluator.TRUE)
+			continue
+		case compiler.OpFalse:
+			vm.push(evaluator.FALSE)
+			continue
+		case compiler.OpPop:
+			vm.pop()
+			continue
+		case compiler.OpAdd, compiler.OpSub, compiler.OpMul, compiler.OpDiv,
+			compiler.OpEqual, compiler.OpNotEqual, compiler.OpGreater, compiler.OpLess,
+			compiler.OpGreaterEqual, compiler.OpLessEqual, compiler.OpIn:
+			right := vm.pop()
+			left := vm.pop()
+
+			if sandbox != nil {
+				result = evaluator.AllocateInfix(operators[op], left, right, f.env)
+				if result != nil { break }
+			}
+
+			result = evaluator.CheckInteger(infix(op, left, right), f.env)
+		case compiler.OpMinus:
+			result = evaluator.CheckInteger(evaluator.PrefixOperation("-", vm.pop()), f.env)
+		case compiler.OpBang:
+			result = evaluator.PrefixOperation("!", vm.pop())
+		case compiler.OpJump:
+			f.ip = vm.operand(f)
+			continue
+		case compiler.OpJumpNotTruthy:
+			target := vm.operand(f)
+			if !evaluator.Truthy(vm.pop()) { f.ip = target }
+			continue
+		case compiler.OpGetName:
+			result = evaluator.Identifier(f.code.Nodes[vm.operand(f)].(*ast.Identifier), f.env)
+		case compiler.OpSetName:
+			evaluator.Bind(f.env, f.code.Nodes[vm.operand(f)].(*ast.Identifier), vm.pop())
+			continue
+		case compiler.OpArray:
+			count := vm.operand(f)
+			if sandbox != nil {
+				result = evaluator.AllocateArray(count, f.env)
+				if result != nil { break }
+			}
+
+			elements := make([]object.Object, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+
+			result = &object.Array{Elements: elements}
+		case compiler.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			result = evaluator.Index(left, index)
+		case compiler.OpMember:
+			name := f.code.Names[vm.operand(f)]
+			result = evaluator.Member(vm.pop(), name, f.env)
+		case compiler.OpFunction:
+			literal := f.code.Nodes[vm.operand(f)].(*ast.FunctionLiteral)
+			vm.push(&object.Function{
+				Parameters:  literal.Parameters,
+				Body:        literal.Body,
+				Env:         f.env,
+				IsGenerator: literal.IsGenerator,
+				Name:        literal.Name,
+			})
+			continue
+		case compiler.OpCall, compiler.OpTailCall:
+			count := int(ins[f.ip])
+			f.ip++
+
+			args := vm.stack[len(vm.stack)-count:]
+			fn := vm.stack[len(vm.stack)-count-1]
+
+			called, err := vm.call(fn, args, op == compiler.OpTailCall, f.code.Positions[start])
+			if called { continue }
+
+			args = append([]object.Object{}, args...)
+			vm.stack = vm.stack[:len(vm.stack)-count-1]
+
+			if err == nil && sandbox != nil { err = evaluator.AllocateCall(fn, args, f.env) }
+			if err != nil {
+				result = err
+				break
+			}
+
+			result = evaluator.ApplyFunction(fn, args, f.env)
+		case compiler.OpReturnValue:
+			value := vm.pop()
+			if vm.ret(value) { return value }
+			continue
+		case compiler.OpEval:
+			result = evaluator.Eval(f.code.Nodes[vm.operand(f)], f.env)
+
+			// Abrupt completions of the node apply to the frame running it
+			if returned, ok := result.(*object.ReturnValue); ok {
+				if tail, ok := returned.Value.(*object.TailCall); ok {
+					result = tail
+				} else {
+					if vm.ret(returned.Value) { return returned.Value }
+					continue
+				}
+			}
+
+			if tail, ok := result.(*object.TailCall); ok {
+				vm.push(tail.Function)
+				vm.stack = append(vm.stack, tail.Arguments...)
+
+				called, err := vm.call(tail.Function, tail.Arguments, true, tail.Token)
+				if called { continue }
+
+				vm.stack = vm.stack[:len(vm.stack)-len(tail.Arguments)-1]
+				if err != nil { return vm.unwind(evaluator.AtCallSite(evaluator.WithPosition(err, tail.Token), tail.Token)) }
+
+				result = evaluator.WithPosition(evaluator.ApplyFunction(tail.Function, tail.Arguments, f.env), tail.Token)
+				result = evaluator.AtCallSite(result, tail.Token)
+			}
+		}
+
+		if err, ok := result.(*object.Error); ok {
+			return vm.unwind(evaluator.AtCallSite(evaluator.WithPosition(err, f.code.Positions[start]), f.code.Positions[start]))
+		}
+
+		vm.push(result)
+	}
+}
+
+/*
+	Records the calls in progress in the trace of err, which stops the program,
+	leaving them.
+*/
+func (vm *VM) unwind(err object.Object) object.Object {
+	for i := len(vm.frames) - 1; i > 0; i-- {
+		err = evaluator.Traced(err, vm.frames[i].function, vm.frames[i].site)
+		if vm.frames[i].sandbox != nil { vm.frames[i].sandbox.Leave() }
+	}
+
+	return err
+}
+
+func (vm *VM) operand(f *frame) int {
+	operand := int(compiler.ReadUint32(f.code.Instructions[f.ip:]))
+	f.ip += 4
+
+	return operand
+}
+
+/*
+	Pushes a frame calling fn if it's a dx function the vm can run, replacing
+	the current frame for tail calls. fn and args are expected on top of the
+	stack, which the call pops. Returns false, leaving the stack untouched,
+	for anything else, which is left to the evaluator, or when the call would
+	go over the depth limit of the sandbox, with its error. site is the
+	position of the call, which tail calls keep from the frame they replace.
+*/
+func (vm *VM) call(fn object.Object, args []object.Object, tail bool, site token.Token) (bool, object.Object) {
+	base := len(vm.stack) - len(args) - 1
+
+	if bound, ok := fn.(*object.BoundMethod); ok {
+		fn = bound.Method
+		args = append([]object.Object{bound.Receiver}, args...)
+	}
+
+	function, ok := fn.(*object.Function)
+	if !ok || function.IsGenerator || len(args) != len(function.Parameters) {
+		return false, nil
+	}
+
+	sandbox := vm.frames[len(vm.frames)-1].env.Sandbox()
+	replaced := tail && len(vm.frames) > 1
+
+	// A tail call stays within the call in progress it replaces
+	if sandbox != nil && !replaced {
+		if err := sandbox.Enter(); err != nil { return false, err }
+	}
+
+	env := object.NewFrame(function.Env, len(function.Parameters))
+	env.SetSandbox(sandbox)
+	for i, param := range function.Parameters {
+		evaluator.Bind(env, param, args[i])
+	}
+
+	code := vm.frames[len(vm.frames)-1].code.Function(function.Body)
+
+	if replaced {
+		base, site = vm.frames[len(vm.frames)-1].base, vm.frames[len(vm.frames)-1].site
+		vm.frames = vm.frames[:len(vm.frames)-1]
+	}
+
+	vm.stack = vm.stack[:base]
+	vm.frames = append(vm.frames, frame{code: code, env: env, base: base, function: function, site: site, sandbox: sandbox})
+
+	return true, nil
+}
+
+// Returns value from the current frame. Returns true when it was the last one.
+func (vm *VM) ret(value object.Object) bool {
+	f := vm.frames[len(vm.frames)-1]
+	vm.frames = vm.frames[:len(vm.frames)-1]
+	if f.sandbox != nil { f.sandbox.Leave() }
+
+	if len(vm.frames) == 0 { return true }
+
+	vm.stack = append(vm.stack[:f.base], value)
+
+	return false
+}
+
+/*
+	Integer arithmetic and comparisons are done in place, the same way the
+	evaluator does them; everything else, overflowing results included, goes
+	through the evaluator.
+*/
+func infix(op compiler.Opcode, left, right object.Object) object.Object {
+	l, lok := left.(*object.Integer)
+	r, rok := right.(*object.Integer)
+
+	if lok && rok {
+		switch op {
+		case compiler.OpAdd:
+			if sum, ok := object.AddInt64(l.Value, r.Value); ok { return integer(sum) }
+		case compiler.OpSub:
+			if diff, ok := object.SubInt64(l.Value, r.Value); ok { return integer(diff) }
+		case compiler.OpMul:
+			if product, ok := object.MulInt64(l.Value, r.Value); ok { return integer(product) }
+		case compiler.OpLess:
+			return nativeBool(l.Value < r.Value)
+		case compiler.OpGreater:
+			return nativeBool(l.Value > r.Value)
+		case compiler.OpLessEqual:
+			return nativeBool(l.Value <= r.Value)
+		case compiler.OpGreaterEqual:
+			return nativeBool(l.Value >= r.Value)
+		case compiler.OpEqual:
+			return nativeBool(l.Value == r.Value)
+		case compiler.OpNotEqual:
+			return nativeBool(l.Value != r.Value)
+		}
+	}
+
+	return evaluator.InfixOperation(operators[op], left, right)
+}
+
+/*
+	Small positive integers are shared rather than allocated for every result.
+	Computed zeros are not: unlike the literal 0, they're truthy.
+*/
+var smallIntegers = func() []*object.Integer {
+	integers := make([]*object.Integer, 1024)
+	for i := range integers {
+		integers[i] = &object.Integer{Value: int64(i)}
+	}
+
+	return integers
+}()
+
+func integer(value int64) *object.Integer {
+	if value > 0 && value < int64(len(smallIntegers)) {
+		return smallIntegers[value]
+	}
+
+	return &object.Integer{Value: value}
+}
+
+var operators = map[compiler.Opcode]string{
+	compiler.OpAdd:      "+",
+	compiler.OpSub:      "-",
+	compiler.OpMul:      "*",
+	compiler.OpDiv:      "/",
+	compiler.OpEqual:    "==",
+	compiler.OpNotEqual: "!=",
+	compiler.OpGreater:  ">",
+	compiler.OpLess:     "<",
+	compiler.OpGreaterEqual: ">=",
+	compiler.OpLessEqual:    "<=",
+	compiler.OpIn:       "in",
+}
+
+func nativeBool(value bool) *object.Boolean {
+	if value { return evaluator.TRUE }
+	return evaluator.FALSE
+}
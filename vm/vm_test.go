@@ -0,0 +1,132 @@
+package vm
+
+import (
+	"context"
+	"dux/compiler"
+	"dux/evaluator"
+	"dux/lexer"
+	"dux/object"
+	"dux/parser"
+	"testing"
+	"time"
+)
+
+const fibonacci = `
+	let fibonacci = fn(n) { if (n < 2) { n } else { fibonacci(n - 1) + fibonacci(n - 2) } };
+	fibonacci(20)
+`
+
+const sum = `
+	let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n * 2 - n) } };
+	sum(100000, 0)
+`
+
+func TestRun(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{fibonacci, "6765"},
+		{sum, "5000050000"},
+		{"let x = 5; let y = x * 2; [x, y, x + y][2]", "15"},
+		{`let greet = fn(name) { "hello " + name }; greet("dux").upper()`, `"HELLO DUX"`},
+		{"let f = fn(x) { if (x > 1) { return x; } 0 }; f(3) + f(1)", "3"},
+		{"match (2) { 1 => 10, _ => 20 }", "20"},
+	}
+
+	for _, tc := range tests {
+		result := run(tc.input)
+
+		if result.Inspect() != tc.expected {
+			t.Errorf("wrong result for %q. want=%s, got=%s", tc.input, tc.expected, result.Inspect())
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	result := run("let f = fn(x) { x + true };\nf(1)")
+
+	err, ok := result.(*object.Error)
+	if !ok { t.Fatalf("result is not an error. got=%T (%+v)", result, result) }
+
+	if err.Message != "type mismatch: INTEGER + BOOLEAN" || err.Position() != "1:19" {
+		t.Errorf("wrong error. got=%q at %s", err.Message, err.Position())
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	tests := []struct{
+		input    string
+		limits   evaluator.Limits
+		timeout  time.Duration
+		expected string
+	}{
+		{"let f = fn(n) { f(n + 1) + 1 }; f(0)", evaluator.Limits{MaxDepth: 100}, 0, "call depth limit exceeded: 100 calls"},
+		{"let f = fn(n) { if (n == 50) { n } else { f(n + 1) + 1 } }; f(0); f(0); f(0)", evaluator.Limits{MaxDepth: 100}, 0, ""},
+		{"let f = fn(n) { f(n + 1) }; f(0)", evaluator.Limits{MaxSteps: 10000}, 0, "step limit exceeded: 10000 steps"},
+		{"let f = fn(n) { f(n + 1) }; f(0)", evaluator.Limits{}, 50 * time.Millisecond, "evaluation stopped: context deadline exceeded"},
+		{"recv(chan())", evaluator.Limits{}, 50 * time.Millisecond, "evaluation stopped: context deadline exceeded"},
+		{`"x" * 1000000000`, evaluator.Limits{MaxMemory: 1 << 20}, 0, "memory limit exceeded: 1048576 bytes"},
+		{`let f = fn(s, n) { if (n == 0) { s } else { f(s + s, n - 1) } }; f("x", 40)`, evaluator.Limits{MaxMemory: 1 << 20}, 0, "memory limit exceeded: 1048576 bytes"},
+		{"to_array(0..100000000)", evaluator.Limits{MaxMemory: 1 << 20}, 0, "memory limit exceeded: 1048576 bytes"},
+		{"let f = fn(n) { [n, n, n, n] }; let g = fn(n) { f(n); g(n + 1) }; g(0)", evaluator.Limits{MaxMemory: 1 << 20}, 0, "memory limit exceeded: 1048576 bytes"},
+		{"let t = spawn fn() { let f = fn(n) { f(n + 1) }; f(0) }(); wait(t)", evaluator.Limits{MaxSteps: 1000}, 0, "step limit exceeded: 1000 steps"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).ParseProgram()
+		env := object.NewEnvironment()
+		evaluator.Resolve(program, env)
+
+		ctx := context.Background()
+		if tc.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+			defer cancel()
+		}
+
+		result := RunContext(ctx, compiler.Compile(program), env, tc.limits)
+
+		err, ok := result.(*object.Error)
+		if tc.expected == "" {
+			if ok { t.Errorf("unexpected error for %q: %s", tc.input, err.Message) }
+			continue
+		}
+
+		if !ok || err.Kind != "LimitError" || err.Message != tc.expected {
+			t.Errorf("wrong result for %q. want=%q, got=%s", tc.input, tc.expected, result.Inspect())
+		}
+	}
+}
+
+func run(input string) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	env := object.NewEnvironment()
+	evaluator.Resolve(program, env)
+
+	return Run(compiler.Compile(program), env)
+}
+
+func BenchmarkFibonacciEvaluator(b *testing.B) { benchmarkEvaluator(b, fibonacci) }
+func BenchmarkFibonacciVM(b *testing.B) { benchmarkVM(b, fibonacci) }
+func BenchmarkSumEvaluator(b *testing.B) { benchmarkEvaluator(b, sum) }
+func BenchmarkSumVM(b *testing.B) { benchmarkVM(b, sum) }
+
+func benchmarkEvaluator(b *testing.B, input string) {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	evaluator.Resolve(program, object.NewEnvironment())
+
+	for i := 0; i < b.N; i++ {
+		evaluator.Eval(program, object.NewEnvironment())
+	}
+}
+
+func benchmarkVM(b *testing.B, input string) {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	evaluator.Resolve(program, object.NewEnvironment())
+	bytecode := compiler.Compile(program)
+
+	for i := 0; i < b.N; i++ {
+		Run(bytecode, object.NewEnvironment())
+	}
+}